@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/history"
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"github.com/spf13/cobra"
+)
+
+var cmdHistory = &cobra.Command{
+	Use:   "history",
+	Short: "Show which flows have run and how they went",
+	Long: `List previous runs of flows, newest first, from both 'ilo run' and the
+automation server. With --stats, summarise each flow instead: how often it
+passes, its flake rate (how often its result differs from the run before), and
+whether its recent passing runs are getting slower or faster.`,
+	Args: cobra.NoArgs,
+	RunE: cmdHistoryImpl,
+}
+
+var (
+	historyProject string
+	historyFlow    string
+	historyStatus  string
+	historyTrigger string
+	historyLimit   int
+	historyStats   bool
+	historyJson    bool
+)
+
+func init() {
+	cmdHistory.Flags().StringVarP(&historyProject, "project", "p", "",
+		"only show runs of the project definition file or directory at this path")
+	cmdHistory.Flags().StringVar(&historyFlow, "flow", "", "only show runs of this flow")
+	cmdHistory.Flags().StringVar(&historyStatus, "status", "", "only show runs which passed, failed or were skipped")
+	cmdHistory.Flags().StringVar(&historyTrigger, "trigger", "", "only show runs started by cli, api or schedule")
+	cmdHistory.Flags().IntVarP(&historyLimit, "limit", "n", 20, "show at most this many runs, or 0 for all")
+	cmdHistory.Flags().BoolVar(&historyStats, "stats", false, "show statistics for each flow")
+	cmdHistory.Flags().BoolVar(&historyJson, "json", false, "output as JSON")
+}
+
+func historyFilter() (history.Filter, error) {
+	filter := history.Filter{
+		Flow:    historyFlow,
+		Status:  exec.Status(historyStatus),
+		Trigger: history.Trigger(historyTrigger),
+	}
+
+	if filter.Status != "" && filter.Status != exec.StatusPassed && filter.Status != exec.StatusFailed &&
+		filter.Status != exec.StatusSkipped {
+		return filter, fmt.Errorf("unknown status '%s', expected passed, failed or skipped", historyStatus)
+	}
+	switch filter.Trigger {
+	case "", history.TriggerCLI, history.TriggerAPI, history.TriggerSchedule:
+	default:
+		return filter, fmt.Errorf("unknown trigger '%s', expected cli, api or schedule", historyTrigger)
+	}
+
+	if historyProject != "" {
+		path, err := iloyml.Locate(historyProject)
+		if err != nil {
+			// The project may have been moved or deleted since it ran
+			if path, err = filepath.Abs(historyProject); err != nil {
+				return filter, err
+			}
+		}
+		filter.Project = path
+	}
+	return filter, nil
+}
+
+func cmdHistoryImpl(cmd *cobra.Command, args []string) error {
+	filter, err := historyFilter()
+	if err != nil {
+		return err
+	}
+
+	records, err := history.NewConfigStore().Query(filter)
+	if err != nil {
+		return err
+	}
+
+	if historyStats {
+		return printHistoryStats(history.Summarize(records))
+	}
+
+	slices.Reverse(records)
+	if historyLimit > 0 && len(records) > historyLimit {
+		records = records[:historyLimit]
+	}
+
+	if historyJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tPROJECT\tFLOW\tTRIGGER\tSTATUS\tDURATION\tRUN")
+	for _, record := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.Start.Local().Format(time.DateTime), projectLabel(record.Project, record.ProjectName),
+			record.Flow, record.Trigger, record.Status, record.Duration().Round(time.Millisecond), record.RunID)
+	}
+	return w.Flush()
+}
+
+// projectLabel returns the name of a project, or its directory if it has
+// no name.
+func projectLabel(path string, name string) string {
+	if name != "" {
+		return name
+	}
+	return filepath.Base(filepath.Dir(path))
+}
+
+// formatMean formats a mean duration of passing runs, which is meaningless
+// if none passed.
+func formatMean(duration time.Duration, passed int) string {
+	if passed == 0 {
+		return "-"
+	}
+	return duration.Round(time.Millisecond).String()
+}
+
+func printHistoryStats(stats []history.FlowStats) error {
+	if historyJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PROJECT\tFLOW\tRUNS\tPASSED\tFLAKE RATE\tMEAN\tRECENT\tTREND\tLAST RUN")
+	for _, flow := range stats {
+		trend := "-"
+		if flow.PreviousDuration != 0 {
+			trend = fmt.Sprintf("%+.0f%%", flow.Trend()*100)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.0f%%\t%.0f%%\t%s\t%s\t%s\t%s\n",
+			projectLabel(flow.Project, flow.ProjectName), flow.Flow, flow.Runs, flow.PassRate()*100,
+			flow.FlakeRate*100, formatMean(flow.MeanDuration, flow.Passed), formatMean(flow.RecentDuration, flow.Passed),
+			trend, flow.Last.Local().Format(time.DateTime))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/history"
+	"github.com/fourls/ilo/internal/runlog"
+	"github.com/spf13/cobra"
+)
+
+var cmdHistoryRetention = &cobra.Command{
+	Use:   "retention",
+	Short: "Show or change how long the history of runs is kept",
+	Long: `Show the history retention settings, or change them with the flags. Ages are
+given as e.g. 90d or 12h, sizes as e.g. 10MB, and 0 removes a limit.`,
+	Args: cobra.NoArgs,
+	RunE: cmdHistoryRetentionImpl,
+}
+
+var (
+	historyMaxAge  string
+	historyMaxSize string
+)
+
+func init() {
+	cmdHistoryRetention.Flags().StringVar(&historyMaxAge, "max-age", "", "remove runs older than this")
+	cmdHistoryRetention.Flags().StringVar(&historyMaxSize, "max-size", "",
+		"remove the oldest runs while the history takes up more than this")
+	cmdHistory.AddCommand(cmdHistoryRetention)
+}
+
+func cmdHistoryRetentionImpl(cmd *cobra.Command, args []string) error {
+	provider := provide.NewConfigProvider[history.Retention]()
+	retention, err := history.LoadRetention(provider)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if cmd.Flags().Changed("max-age") {
+		if _, err := runlog.ParseAge(historyMaxAge); err != nil {
+			return err
+		}
+		retention.MaxAge = historyMaxAge
+		changed = true
+	}
+	if cmd.Flags().Changed("max-size") {
+		if _, err := runlog.ParseSize(historyMaxSize); err != nil {
+			return err
+		}
+		retention.MaxSize = historyMaxSize
+		changed = true
+	}
+
+	if changed {
+		if err := provider.Save("history", &retention, provide.YamlMarshal); err != nil {
+			return err
+		}
+		if _, err := history.NewConfigStore().Prune(retention, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("max age:  %s\n", retention.MaxAge)
+	fmt.Printf("max size: %s\n", retention.MaxSize)
+	return nil
+}
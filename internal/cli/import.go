@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"github.com/fourls/ilo/internal/scaffold"
+	"github.com/spf13/cobra"
+)
+
+var cmdImport = &cobra.Command{
+	Use:   "import <source>...",
+	Short: "Create an ilo.yml from a Makefile, package.json or GitHub workflows",
+	Long: `Create an ilo.yml from the tasks defined for other tools. Each source can be
+a Makefile, whose targets become flows that need their prerequisites; a
+package.json, whose scripts become flows; or a GitHub Actions workflow, or
+a directory of them, whose jobs become flows made of their run steps.
+Anything which can't be translated is kept as a comment.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: cmdImportImpl,
+}
+
+var (
+	importOutput string
+	importForce  bool
+)
+
+func init() {
+	cmdImport.Flags().StringVarP(&importOutput, "output", "o", iloyml.FileNames[0],
+		"path to write the project definition to, or - to print it")
+	cmdImport.Flags().BoolVarP(&importForce, "force", "f", false, "replace an existing project definition")
+}
+
+func cmdImportImpl(cmd *cobra.Command, args []string) error {
+	project, err := scaffold.Import(args)
+	if err != nil {
+		return err
+	}
+
+	if importOutput == "-" {
+		return iloyml.Write(os.Stdout, project.Document)
+	}
+
+	path, err := filepath.Abs(importOutput)
+	if err != nil {
+		return err
+	}
+	if project.Document.Name == "" {
+		project.Document.Name = filepath.Base(filepath.Dir(path))
+	}
+
+	if err := registerTools(filepath.Dir(path), project.Tools); err != nil {
+		return err
+	}
+	if err := iloyml.Create(path, project.Document, importForce); err != nil {
+		return err
+	}
+
+	names := make([]string, len(project.Document.Flows))
+	for i, flow := range project.Document.Flows {
+		names[i] = flow.Name
+	}
+	fmt.Printf("Created %s with flows: %s\n", path, strings.Join(names, ", "))
+	return nil
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"github.com/fourls/ilo/internal/scaffold"
+	"github.com/spf13/cobra"
+)
+
+var cmdInit = &cobra.Command{
+	Use:   "init [dir]",
+	Short: "Create an ilo.yml with flows for the build systems a project uses",
+	Long: `Create an ilo.yml with build, test and lint flows, based on the go.mod,
+package.json, Cargo.toml, Makefile, pyproject.toml and similar files in the
+directory. Tools the flows use which aren't in the toolbox yet are found on
+PATH and added to the user toolbox.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: cmdInitImpl,
+}
+
+var initForce bool
+
+func init() {
+	cmdInit.Flags().BoolVarP(&initForce, "force", "f", false, "replace an existing project definition")
+}
+
+func cmdInitImpl(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range iloyml.FileNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil && !initForce {
+			return fmt.Errorf("'%s' already exists, use --force to replace it", filepath.Join(dir, name))
+		}
+	}
+
+	project, err := scaffold.Detect(dir)
+	if err != nil {
+		return err
+	}
+
+	doc := project.Document
+	doc.Comment = "Generated by ilo init. Run a flow with `ilo run <flow>`."
+	if len(doc.Flows) == 0 {
+		doc.Flows = []iloyml.FlowDocument{{
+			Name:    "hello",
+			Comment: "No build system was recognised, so add your own flows here",
+			Steps:   []iloyml.StepDocument{{Echo: "Hello from ilo"}},
+		}}
+	}
+
+	if err := registerTools(dir, project.Tools); err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, iloyml.FileNames[0])
+	if err := iloyml.Create(path, doc, initForce); err != nil {
+		return err
+	}
+
+	names := make([]string, len(doc.Flows))
+	for i, flow := range doc.Flows {
+		names[i] = flow.Name
+	}
+	fmt.Printf("Created %s with flows: %s\n", path, strings.Join(names, ", "))
+	if len(project.Sources) > 0 {
+		fmt.Printf("Detected from: %s\n", strings.Join(project.Sources, ", "))
+	}
+	return nil
+}
+
+// registerTools adds the tools which the project can't find yet to the
+// user toolbox, from PATH.
+func registerTools(dir string, tools []string) error {
+	provider := provide.NewConfigProvider[toolbox.Toolbox]()
+	available, err := toolbox.LoadLayered(provider, dir, nil)
+	if err != nil {
+		return err
+	}
+
+	user, err := provider.Load("toolbox", provide.YamlUnmarshal[toolbox.Toolbox])
+	if err != nil {
+		return err
+	}
+	if *user == nil {
+		*user = make(toolbox.Toolbox)
+	}
+
+	added := false
+	for _, name := range tools {
+		if _, err := available.Find(name, ""); err == nil {
+			continue
+		}
+
+		if err := user.FindAndAdd(name); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v, register it with 'ilo tool add'\n", err)
+			continue
+		}
+
+		tool := (*user)[name]
+		if tool.Version != "" {
+			fmt.Printf("Registered %s %s at %s\n", name, tool.Version, tool.Path)
+		} else {
+			fmt.Printf("Registered %s at %s\n", name, tool.Path)
+		}
+		added = true
+	}
+
+	if !added {
+		return nil
+	}
+	return provider.Save("toolbox", user, provide.YamlMarshal)
+}
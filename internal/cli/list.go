@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fourls/ilo/internal/display"
+	"github.com/fourls/ilo/internal/ilofile"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var cmdList = &cobra.Command{
+	Use:   "list",
+	Short: "List the flows in a project",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project, err := loadProject(projectPath)
+		if err != nil {
+			return err
+		}
+
+		printFlows(os.Stdout, project)
+		return nil
+	},
+}
+
+func init() {
+	cmdList.Flags().StringVarP(&projectPath, "project", "p", ".", projectFlagUsage)
+}
+
+// flowItems returns the flows of a project in name order, to be listed or
+// picked from.
+func flowItems(project *ilofile.Definition) []display.PickerItem {
+	items := make([]display.PickerItem, 0, len(project.Flows))
+	for _, flow := range project.Flows {
+		items = append(items, display.PickerItem{Name: flow.Name, Description: flow.Description})
+	}
+	slices.SortFunc(items, func(a, b display.PickerItem) int {
+		switch {
+		case a.Name < b.Name:
+			return -1
+		case a.Name > b.Name:
+			return 1
+		}
+		return 0
+	})
+	return items
+}
+
+// printFlows lists the flows of a project with their descriptions, and
+// when the automation server will next run them on schedule.
+func printFlows(w io.Writer, project *ilofile.Definition) {
+	items := flowItems(project)
+	now := time.Now()
+
+	nameWidth, descriptionWidth := 0, 0
+	for _, item := range items {
+		nameWidth = max(nameWidth, display.DisplayWidth(item.Name))
+		descriptionWidth = max(descriptionWidth, display.DisplayWidth(item.Description))
+	}
+
+	for _, item := range items {
+		line := display.Pad(item.Name, nameWidth)
+		if descriptionWidth > 0 {
+			line += "  " + display.Pad(item.Description, descriptionWidth)
+		}
+		if next := project.Flows[item.Name].NextRun(now); !next.IsZero() {
+			line += "  next run " + next.Local().Format(time.DateTime)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
+// pickFlows asks which flows to run when ilo is used interactively, and
+// lists the flows otherwise.
+func pickFlows(project *ilofile.Definition) ([]string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		printFlows(os.Stdout, project)
+		return nil, nil
+	}
+
+	if len(project.Flows) == 0 {
+		return nil, fmt.Errorf("no flows defined in '%s'", project.Path)
+	}
+	return display.Pick(os.Stdin, os.Stderr, "Run flows:", flowItems(project))
+}
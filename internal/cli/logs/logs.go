@@ -0,0 +1,133 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/runlog"
+	"github.com/spf13/cobra"
+)
+
+var CmdLogs = &cobra.Command{
+	Use:   "logs [run-id|last]",
+	Short: "Show the output of previous runs",
+	Long: `Without arguments, list the runs that have been logged, newest first.
+Given a run ID, a unique prefix of one, or "last" for the newest run, print the
+output of every step in that run, or of a single flow or step.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: cmdLogsImpl,
+}
+
+var (
+	logsFlow string
+	logsStep int
+)
+
+func init() {
+	CmdLogs.Flags().StringVar(&logsFlow, "flow", "", "only show the output of this flow")
+	CmdLogs.Flags().IntVar(&logsStep, "step", 0, "only show the output of this step, numbered from 1")
+
+	CmdLogs.AddCommand(cmdLogsPrune)
+	CmdLogs.AddCommand(cmdLogsRetention)
+}
+
+func cmdLogsImpl(cmd *cobra.Command, args []string) error {
+	store := runlog.NewCacheStore()
+
+	if len(args) == 0 {
+		if logsFlow != "" || logsStep != 0 {
+			return errors.New("--flow and --step need a run, such as 'last'")
+		}
+		return listRuns(store)
+	}
+
+	run, err := store.Find(args[0])
+	if err != nil {
+		return err
+	}
+	return showRun(store, run)
+}
+
+func listRuns(store runlog.Store) error {
+	runs, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tSTATUS\tPROJECT\tFLOWS\tSTARTED\tDURATION")
+	for _, run := range runs {
+		duration := "-"
+		if !run.End.IsZero() {
+			duration = run.Duration().Round(time.Millisecond).String()
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			run.ID, run.Status, run.ProjectName, len(run.Flows),
+			run.Start.Local().Format(time.DateTime), duration)
+	}
+	return w.Flush()
+}
+
+func showRun(store runlog.Store, run runlog.Run) error {
+	shown := 0
+	for _, flow := range run.Flows {
+		if logsFlow != "" && flow.Name != logsFlow {
+			continue
+		}
+
+		for _, step := range flow.Steps {
+			if logsStep != 0 && step.Index+1 != logsStep {
+				continue
+			}
+			shown += 1
+
+			// A single step is printed bare, so it can be piped elsewhere
+			if logsStep == 0 {
+				fmt.Printf("==> %s / %d: %s (%s)\n", flow.Name, step.Index+1, step.Text, describeStep(step))
+			}
+			if err := printLog(store, run, step); err != nil {
+				return err
+			}
+		}
+	}
+
+	if shown == 0 {
+		return fmt.Errorf("run '%s' has no matching steps", run.ID)
+	}
+	return nil
+}
+
+func describeStep(step runlog.Step) string {
+	switch step.Status {
+	case exec.StatusPassed:
+		return fmt.Sprintf("passed in %s", step.End.Sub(step.Start).Round(time.Millisecond))
+	case exec.StatusFailed:
+		return fmt.Sprintf("failed after %s: %s", step.End.Sub(step.Start).Round(time.Millisecond), step.Error)
+	default:
+		return string(step.Status)
+	}
+}
+
+func printLog(store runlog.Store, run runlog.Run, step runlog.Step) error {
+	if step.Log == "" {
+		return nil
+	}
+
+	file, err := os.Open(store.LogPath(run, step))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(os.Stdout, file)
+	return err
+}
@@ -0,0 +1,32 @@
+package logs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/runlog"
+	"github.com/spf13/cobra"
+)
+
+var cmdLogsPrune = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove run logs outside the retention settings",
+	Long: `Remove the logs of runs older than the maximum age, then of the oldest runs
+until the logs fit in the maximum size. This also happens after every run.`,
+	Args: cobra.NoArgs,
+	RunE: cmdLogsPruneImpl,
+}
+
+func cmdLogsPruneImpl(cmd *cobra.Command, args []string) error {
+	retention, err := runlog.LoadRetention(provide.NewConfigProvider[runlog.Retention]())
+	if err != nil {
+		return err
+	}
+
+	removed, err := runlog.NewCacheStore().Prune(retention, time.Now())
+	for _, id := range removed {
+		fmt.Printf("Removed run %s\n", id)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package logs
+
+import (
+	"fmt"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/runlog"
+	"github.com/spf13/cobra"
+)
+
+var cmdLogsRetention = &cobra.Command{
+	Use:   "retention",
+	Short: "Show or change how long run logs are kept",
+	Long: `Show the log retention settings, or change them with the flags. Ages are
+given as e.g. 30d or 12h, sizes as e.g. 500MB or 2GB, and 0 removes a limit.`,
+	Args: cobra.NoArgs,
+	RunE: cmdLogsRetentionImpl,
+}
+
+var (
+	retentionMaxAge  string
+	retentionMaxSize string
+)
+
+func init() {
+	cmdLogsRetention.Flags().StringVar(&retentionMaxAge, "max-age", "", "remove runs older than this")
+	cmdLogsRetention.Flags().StringVar(&retentionMaxSize, "max-size", "",
+		"remove the oldest runs while all logs take up more than this")
+}
+
+func cmdLogsRetentionImpl(cmd *cobra.Command, args []string) error {
+	provider := provide.NewConfigProvider[runlog.Retention]()
+	retention, err := runlog.LoadRetention(provider)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if cmd.Flags().Changed("max-age") {
+		if _, err := runlog.ParseAge(retentionMaxAge); err != nil {
+			return err
+		}
+		retention.MaxAge = retentionMaxAge
+		changed = true
+	}
+	if cmd.Flags().Changed("max-size") {
+		if _, err := runlog.ParseSize(retentionMaxSize); err != nil {
+			return err
+		}
+		retention.MaxSize = retentionMaxSize
+		changed = true
+	}
+
+	if changed {
+		if err := provider.Save("logs", &retention, provide.YamlMarshal); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("max age:  %s\n", retention.MaxAge)
+	fmt.Printf("max size: %s\n", retention.MaxSize)
+	return nil
+}
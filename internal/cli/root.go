@@ -4,20 +4,50 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/fourls/ilo/internal/cli/logs"
 	"github.com/fourls/ilo/internal/cli/server"
 	"github.com/fourls/ilo/internal/cli/tool"
+	"github.com/fourls/ilo/internal/display"
 	"github.com/spf13/cobra"
 )
 
 var cmdRoot = &cobra.Command{
-	Use:   "ilo",
-	Short: "A simple task runner.",
+	Use:               "ilo",
+	Short:             "A simple task runner.",
+	PersistentPreRunE: applyDisplayFlags,
+	// With no command, pick flows to run as `ilo run` does
+	Args: cobra.NoArgs,
+	RunE: runCmdImpl,
 }
 
+var (
+	colorFlag string
+	asciiFlag bool
+)
+
 func init() {
+	cmdRoot.PersistentFlags().StringVar(&colorFlag, "color", string(display.ColorAuto),
+		"colour output: auto (when writing to a terminal and NO_COLOR is unset), always or never")
+	cmdRoot.PersistentFlags().BoolVar(&asciiFlag, "ascii", false, "draw borders and symbols with ASCII characters only")
+
 	cmdRoot.AddCommand(cmdRun)
+	cmdRoot.AddCommand(cmdList)
+	cmdRoot.AddCommand(cmdInit)
+	cmdRoot.AddCommand(cmdImport)
+	cmdRoot.AddCommand(cmdHistory)
 	cmdRoot.AddCommand(tool.CmdTool)
 	cmdRoot.AddCommand(server.CmdServer)
+	cmdRoot.AddCommand(logs.CmdLogs)
+}
+
+func applyDisplayFlags(cmd *cobra.Command, args []string) error {
+	mode, err := display.ParseColorMode(colorFlag)
+	if err != nil {
+		return err
+	}
+
+	display.DefaultOptions = display.Options{Color: mode, ASCII: asciiFlag}
+	return nil
 }
 
 func Execute() {
@@ -2,64 +2,195 @@ package cli
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/fourls/ilo/internal/data/provide"
 	"github.com/fourls/ilo/internal/data/toolbox"
-	"github.com/fourls/ilo/internal/display"
 	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/history"
+	"github.com/fourls/ilo/internal/ilofile"
 	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"github.com/fourls/ilo/internal/report"
+	"github.com/fourls/ilo/internal/runlog"
 	"github.com/spf13/cobra"
+
+	// Register the observers available to --output
+	_ "github.com/fourls/ilo/internal/display"
 )
 
 var cmdRun = &cobra.Command{
-	Use:  "run [flows...]",
-	RunE: runCmdImpl,
+	Use:   "run [flows...]",
+	Short: "Run flows, or pick flows to run if none are given",
+	RunE:  runCmdImpl,
 }
 
-var projectPath string
+// projectFlagUsage describes the --project flag of commands which use a
+// project.
+const projectFlagUsage = "path to the project definition file, or a directory to search upwards from for ilo.yml, ilo.yaml or .ilo.yml"
+
+var (
+	projectPath string
+	outputSpecs []string
+	reportSpecs []string
+	profilePath string
+)
+
+// reportQueueSize is the number of events queued for each report observer.
+const reportQueueSize = 256
 
 func init() {
-	var wd, _ = os.Getwd()
-	cmdRun.Flags().StringVarP(&projectPath, "project", "p", wd, "path to project definition file")
+	cmdRun.Flags().StringVarP(&projectPath, "project", "p", ".", projectFlagUsage)
+	cmdRun.Flags().StringArrayVarP(&outputSpecs, "output", "o", []string{"auto"},
+		"output format: auto (github in GitHub Actions, progress on a terminal, text elsewhere), github, progress, text or jsonl, optionally written to a file as <format>=<path> (repeatable)")
+	cmdRun.Flags().StringArrayVar(&reportSpecs, "report", nil,
+		"write a report as <format>=<path>, where format is junit, tap or trace (repeatable)")
+	cmdRun.Flags().StringVar(&profilePath, "profile", "",
+		"write a Chrome trace of the time spent in each flow and step to this path")
 }
 
-func runCmdImpl(cmd *cobra.Command, args []string) error {
-	var stat, _ = os.Stat(projectPath)
-	if stat.IsDir() {
-		projectPath = filepath.Join(projectPath, "ilo.yml")
+func runCmdImpl(cmd *cobra.Command, args []string) (err error) {
+	project, err := loadProject(projectPath)
+	if err != nil {
+		return err
 	}
 
-	if !filepath.IsAbs(projectPath) {
-		var err error
-		projectPath, err = filepath.Abs(projectPath)
-		if err != nil {
+	if len(args) == 0 {
+		if args, err = pickFlows(project); err != nil || len(args) == 0 {
 			return err
 		}
 	}
 
-	project, err := iloyml.New(projectPath)
+	tb, err := toolbox.LoadLayered(
+		provide.NewConfigProvider[toolbox.Toolbox](),
+		filepath.Dir(project.Path),
+		project.Toolbox)
 	if err != nil {
 		return err
 	}
 
-	provider := provide.NewConfigProvider[toolbox.Toolbox]()
-	toolbox, _ := provider.Load(
-		"toolbox",
-		provide.YamlUnmarshal[toolbox.Toolbox])
+	flows, err := project.Plan(args...)
+	if err != nil {
+		return err
+	}
 
-	log := log.New(os.Stdout, "", 0)
-	observer := display.NewObserver(project, log)
+	if err := exec.CheckTools(project, flows, tb); err != nil {
+		return err
+	}
 
-	for _, flowName := range args {
-		flow, exists := project.Flows[flowName]
-		if !exists {
-			return fmt.Errorf("no flow '%s' exists", flowName)
-		}
-		exec.RunFlow(flow, exec.RunStep, *toolbox, &observer)
+	runID := exec.NewRunID()
+	observers, err := newObservers(project, runID)
+	if err != nil {
+		return err
 	}
 
+	historyStore := history.NewConfigStore()
+	observers.Add(history.NewObserver(historyStore, project, history.TriggerCLI))
+	defer pruneHistory(historyStore)
+
+	logs := runlog.NewCacheStore()
+	if writer, err := logs.NewWriter(runID, project); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: run will not be logged: %v\n", err)
+	} else {
+		observers.Add(writer)
+		defer pruneLogs(logs)
+	}
+	defer func() {
+		if closeErr := observers.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	passed := exec.RunPlan(runID, flows, exec.RunStep, tb, observers)
+
+	// Failing flows have already been reported, so only the exit status is left
+	cmd.SilenceUsage = true
+	failed := 0
+	for _, flow := range flows {
+		if !passed[flow.Name] {
+			failed += 1
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d flows did not pass", failed, len(flows))
+	}
 	return nil
 }
+
+// loadProject reads the project definition at path, or the nearest one
+// found from the directory at path.
+func loadProject(path string) (*ilofile.Definition, error) {
+	path, err := iloyml.Locate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := iloyml.New(path)
+	if err != nil {
+		return nil, fmt.Errorf("load project '%s': %w", path, err)
+	}
+	return project, nil
+}
+
+// pruneLogs applies the log retention settings once a run has finished.
+func pruneLogs(logs runlog.Store) {
+	retention, err := runlog.LoadRetention(provide.NewConfigProvider[runlog.Retention]())
+	if err == nil {
+		_, err = logs.Prune(retention, time.Now())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: old run logs not removed: %v\n", err)
+	}
+}
+
+// pruneHistory applies the history retention settings once a run has
+// finished.
+func pruneHistory(store history.Store) {
+	retention, err := history.LoadRetention(provide.NewConfigProvider[history.Retention]())
+	if err == nil {
+		_, err = store.Prune(retention, time.Now())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: old history not removed: %v\n", err)
+	}
+}
+
+// newObservers creates an observer for every --output and --report flag.
+// Reports are written from a queue, so that they don't hold up output.
+func newObservers(project *ilofile.Definition, runID string) (*exec.MultiObserver, error) {
+	opts := exec.ObserverOptions{Project: project, RunID: runID}
+	observers := exec.NewMultiObserver()
+
+	for _, spec := range outputSpecs {
+		kind, path := exec.ParseObserverSpec(spec)
+		observer, err := exec.NewRegisteredObserver(kind, path, opts)
+		if err != nil {
+			observers.Close()
+			return nil, err
+		}
+		observers.Add(observer)
+	}
+
+	specs := reportSpecs
+	if profilePath != "" {
+		specs = append(specs, string(report.Trace)+"="+profilePath)
+	}
+
+	for _, spec := range specs {
+		kind, path := exec.ParseObserverSpec(spec)
+		if path == "" {
+			observers.Close()
+			return nil, fmt.Errorf("invalid report '%s': expected <format>=<path>", spec)
+		}
+
+		observer, err := exec.NewRegisteredObserver(kind, path, opts)
+		if err != nil {
+			observers.Close()
+			return nil, err
+		}
+		observers.Add(exec.NewAsyncObserver(observer, reportQueueSize))
+	}
+
+	return observers, nil
+}
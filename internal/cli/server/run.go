@@ -37,7 +37,7 @@ func cmdServerRunImpl(cmd *cobra.Command, args []string) error {
 	err := server.Run("localhost:8116")
 
 	if err != nil {
-		display.HorizontalRule{Footer: fmt.Sprintf("ERROR: %s", err.Error())}.Print(log)
+		display.HorizontalRule{Footer: fmt.Sprintf("ERROR: %s", err.Error()), FooterStyle: display.StyleFailed}.Print(log)
 	}
 	return err
 }
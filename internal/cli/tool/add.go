@@ -2,37 +2,89 @@ package tool
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
-	"github.com/fourls/ilo/internal/data/provide"
 	"github.com/fourls/ilo/internal/data/toolbox"
 	"github.com/spf13/cobra"
 )
 
 var cmdToolAdd = &cobra.Command{
-	Use:  "add",
-	RunE: cmdToolAddImpl,
+	Use:   "add <name[@version]>... | add <name[@version]> <path>",
+	Short: "Register tools found on PATH, or a tool at a specific path",
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  cmdToolAddImpl,
+}
+
+// isPathArg reports whether an argument refers to an executable path
+// rather than a tool name.
+func isPathArg(arg string) bool {
+	return strings.ContainsRune(arg, '/') || strings.ContainsRune(arg, filepath.Separator)
 }
 
 func cmdToolAddImpl(cmd *cobra.Command, args []string) error {
-	provider := provide.NewConfigProvider[toolbox.Toolbox]()
-	toolbox, err := provider.Load("toolbox",
-		provide.YamlUnmarshal[toolbox.Toolbox])
+	provider, tb, err := loadToolbox()
 	if err != nil {
 		return err
 	}
 
-	if *toolbox == nil {
-		// We want to be able to update the toolbox
-		*toolbox = make(map[string]string)
+	if len(args) == 2 && isPathArg(args[1]) {
+		if err := addToolAtPath(tb, args[0], toolbox.Tool{Path: args[1]}, false); err != nil {
+			return err
+		}
+	} else {
+		for _, ref := range args {
+			err := tb.FindAndAdd(ref)
+			if err != nil {
+				return err
+			}
+			printRegistered(tb, ref)
+		}
 	}
 
-	for _, name := range args {
-		err := toolbox.FindAndAdd(name)
-		if err != nil {
-			return err
+	return saveToolbox(provider, tb)
+}
+
+// addToolAtPath registers the executable at path under ref, detecting its
+// version if ref doesn't specify one. If makeDefault is set, the tool also
+// replaces any existing default for its name.
+func addToolAtPath(tb toolbox.Toolbox, ref string, tool toolbox.Tool, makeDefault bool) error {
+	path, err := filepath.Abs(tool.Path)
+	if err != nil {
+		return err
+	}
+	tool.Path = path
+
+	if err := toolbox.CheckExecutable(path); err != nil {
+		return fmt.Errorf("add tool '%s': %w", ref, err)
+	}
+
+	name, version := toolbox.ParseRef(ref)
+	if version == "" {
+		// A version is optional, as not every tool can report one.
+		tool.Version, _ = toolbox.DetectVersion(name, path)
+		if makeDefault {
+			tb[name] = tool
 		}
-		fmt.Printf("Registered $%s at path '%s'\n", name, (*toolbox)[name])
+	} else {
+		tool.Version = version
 	}
 
-	return provider.Save("toolbox", toolbox, provide.YamlMarshal)
+	tb.Add(name, tool)
+	version = tool.Version
+	printRegistered(tb, toolbox.Ref(name, version))
+	return nil
+}
+
+func printRegistered(tb toolbox.Toolbox, ref string) {
+	name, version := toolbox.ParseRef(ref)
+	versions := tb.Versions(name)
+	tool, _ := tb.Find(name, version)
+
+	if len(versions) > 0 {
+		fmt.Printf("Registered $%s at path '%s' (versions: %s)\n",
+			name, tool.Path, strings.Join(versions, ", "))
+	} else {
+		fmt.Printf("Registered $%s at path '%s'\n", name, tool.Path)
+	}
 }
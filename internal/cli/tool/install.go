@@ -0,0 +1,66 @@
+package tool
+
+import (
+	"fmt"
+
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/fourls/ilo/internal/data/toolcache"
+	"github.com/spf13/cobra"
+)
+
+var cmdToolInstall = &cobra.Command{
+	Use:   "install <name>@<version> --from <archive|dir>",
+	Short: "Install a tool version from a local archive into ilo's tool cache",
+	Long: `Install a version of a tool from a .tar.gz, .tgz, .tar or .zip archive, from a
+mirror directory containing <name>-<version>.<ext>, or from a directory holding the
+unpacked tool. The tool is copied into ilo's tool cache and registered in the toolbox.
+
+The SHA-256 checksum of an archive is verified against --sha256 or, if it isn't
+given, against a <archive>.sha256 or SHA256SUMS file next to the archive. For an
+unpacked directory, the checksum of the tool's executable is verified in the same
+way, with the checksum file next to the executable. Nothing is installed without a
+checksum.`,
+	Args: cobra.ExactArgs(1),
+	RunE: cmdToolInstallImpl,
+}
+
+var (
+	installFrom   string
+	installSha256 string
+	installBinary string
+	installForce  bool
+)
+
+func init() {
+	cmdToolInstall.Flags().StringVar(&installFrom, "from", "", "archive, mirror directory or unpacked directory to install from")
+	cmdToolInstall.Flags().StringVar(&installSha256, "sha256", "", "expected SHA-256 checksum of the archive, or of the executable in an unpacked directory")
+	cmdToolInstall.Flags().StringVar(&installBinary, "bin", "", "path of the executable within the installed tree")
+	cmdToolInstall.Flags().BoolVar(&installForce, "force", false, "replace an existing installation of the same version")
+	cmdToolInstall.MarkFlagRequired("from")
+}
+
+func cmdToolInstallImpl(cmd *cobra.Command, args []string) error {
+	name, version := toolbox.ParseRef(args[0])
+	if version == "" {
+		return fmt.Errorf("install tool '%s': a version is required, e.g. %s@1.0.0", name, name)
+	}
+
+	provider, tb, err := loadToolbox()
+	if err != nil {
+		return err
+	}
+
+	path, err := toolcache.NewConfigCache().Install(name, version, installFrom, toolcache.InstallOptions{
+		Sha256: installSha256,
+		Binary: installBinary,
+		Force:  installForce,
+	})
+	if err != nil {
+		return err
+	}
+
+	tb.Add(name, toolbox.Tool{Path: path, Version: version})
+	printRegistered(tb, toolbox.Ref(name, version))
+
+	return saveToolbox(provider, tb)
+}
@@ -0,0 +1,149 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"github.com/spf13/cobra"
+)
+
+var cmdToolList = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List registered tools",
+	Args:    cobra.NoArgs,
+	RunE:    cmdToolListImpl,
+}
+
+var (
+	listJson     bool
+	listResolved bool
+	listProject  string
+)
+
+func init() {
+	cmdToolList.Flags().BoolVar(&listJson, "json", false, "output the toolbox as JSON")
+	cmdToolList.Flags().BoolVar(&listResolved, "resolved", false,
+		"list the tools a project sees after layering project, user and system toolboxes")
+	cmdToolList.Flags().StringVarP(&listProject, "project", "p", ".",
+		"path to project definition file, used with --resolved")
+}
+
+type toolListing struct {
+	Ref     string            `json:"ref"`
+	Name    string            `json:"name"`
+	Version string            `json:"version,omitempty"`
+	Default bool              `json:"default"`
+	Path    string            `json:"path"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+	Source  string            `json:"source,omitempty"`
+	Exists  bool              `json:"exists"`
+	Error   string            `json:"error,omitempty"`
+}
+
+func listTools(tb toolbox.Toolbox) []toolListing {
+	entries := tb.Entries()
+	listings := make([]toolListing, len(entries))
+
+	for i, entry := range entries {
+		listings[i] = toolListing{
+			Ref:     entry.Ref,
+			Name:    entry.Name,
+			Version: entry.Version,
+			Default: entry.Default,
+			Path:    entry.Path,
+			Args:    entry.Args,
+			Env:     entry.Env,
+			Dir:     entry.Dir,
+			Source:  entry.Source,
+			Exists:  true,
+		}
+		if err := toolbox.CheckExecutable(entry.Path); err != nil {
+			listings[i].Exists = false
+			listings[i].Error = err.Error()
+		}
+	}
+	return listings
+}
+
+func cmdToolListImpl(cmd *cobra.Command, args []string) error {
+	var tb toolbox.Toolbox
+	var err error
+	if listResolved {
+		tb, err = loadResolvedToolbox(listProject)
+	} else {
+		_, tb, err = loadToolbox()
+	}
+	if err != nil {
+		return err
+	}
+
+	listings := listTools(tb)
+
+	if listJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(listings)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	if listResolved {
+		fmt.Fprintln(w, "TOOL\tVERSION\tPATH\tSTATUS\tSOURCE")
+	} else {
+		fmt.Fprintln(w, "TOOL\tVERSION\tPATH\tSTATUS")
+	}
+
+	for _, listing := range listings {
+		status := "ok"
+		if !listing.Exists {
+			status = "missing"
+		}
+		fmt.Fprintf(w, "$%s\t%s\t%s\t%s", listing.Ref, listing.Version, listing.Path, status)
+		if listResolved {
+			fmt.Fprintf(w, "\t%s", listing.Source)
+		}
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// loadResolvedToolbox loads the layered toolbox for the project at path,
+// which may be a project definition file or a directory containing one.
+// If there is no project definition, its directory is still searched for a
+// project toolbox.
+func loadResolvedToolbox(path string) (toolbox.Toolbox, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	projectDir := path
+	var inline toolbox.Toolbox
+
+	if stat, err := os.Stat(path); err == nil && !stat.IsDir() {
+		projectDir = filepath.Dir(path)
+	} else {
+		path = filepath.Join(path, "ilo.yml")
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		project, err := iloyml.New(path)
+		if err != nil {
+			return nil, err
+		}
+		inline = project.Toolbox
+	}
+
+	return toolbox.LoadLayered(
+		provide.NewConfigProvider[toolbox.Toolbox](),
+		projectDir,
+		inline)
+}
@@ -0,0 +1,140 @@
+package tool
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/fourls/ilo/internal/data/toolcache"
+	"github.com/fourls/ilo/internal/ilofile"
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"github.com/fourls/ilo/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var cmdToolPrune = &cobra.Command{
+	Use:   "prune [project...]",
+	Short: "List or remove installed tool versions that no project references",
+	Long: `List tool versions in ilo's tool cache that aren't referenced by the given
+projects or by any project registered with the automation server, and remove them
+along with their toolbox entries when --force is given. A version is referenced if a
+project pins it, requires it, uses it in a step, or uses the tool's default while
+the default is that version. Defaults to the project in the current directory.
+
+Projects which are neither given nor registered aren't known to ilo, so check the
+list before removing anything.`,
+	RunE: cmdToolPruneImpl,
+}
+
+var pruneForce bool
+
+func init() {
+	cmdToolPrune.Flags().BoolVar(&pruneForce, "force", false, "remove the versions instead of only listing them")
+}
+
+func cmdToolPruneImpl(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	var paths []string
+	for _, path := range args {
+		path, err := iloyml.Locate(path)
+		if err != nil {
+			return fmt.Errorf("prune tools: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	registered, err := provide.NewConfigProvider[server.ProjectFile]().Load("projects", provide.YamlUnmarshal[server.ProjectFile])
+	if err != nil {
+		return fmt.Errorf("prune tools: load registered projects: %w", err)
+	}
+	for _, entry := range registered.Projects {
+		if !slices.Contains(paths, entry.Path) {
+			paths = append(paths, entry.Path)
+		}
+	}
+
+	var projects []*ilofile.Definition
+	for _, path := range paths {
+		project, err := iloyml.New(path)
+		if err != nil {
+			return fmt.Errorf("prune tools: %w", err)
+		}
+		projects = append(projects, project)
+	}
+
+	provider, tb, err := loadToolbox()
+	if err != nil {
+		return err
+	}
+
+	cache := toolcache.NewConfigCache()
+	installed, err := cache.List()
+	if err != nil {
+		return err
+	}
+
+	for _, version := range installed {
+		if isReferenced(version, projects, tb, cache) {
+			continue
+		}
+
+		if !pruneForce {
+			fmt.Printf("Would remove $%s from '%s'\n", toolbox.Ref(version.Name, version.Version), version.Dir)
+			continue
+		}
+
+		fmt.Printf("Removing $%s from '%s'\n", toolbox.Ref(version.Name, version.Version), version.Dir)
+
+		if err := cache.Remove(version.Name, version.Version); err != nil {
+			return err
+		}
+		for ref, tool := range tb {
+			if cache.Contains(version, tool.Path) {
+				delete(tb, ref)
+			}
+		}
+	}
+
+	if !pruneForce {
+		return nil
+	}
+	return saveToolbox(provider, tb)
+}
+
+func isReferenced(installed toolcache.Installed, projects []*ilofile.Definition, tb toolbox.Toolbox, cache toolcache.Cache) bool {
+	for _, project := range projects {
+		if pin, exists := project.Tools[installed.Name]; exists && toolbox.MatchVersion(pin, installed.Version) {
+			return true
+		}
+
+		if requirement, exists := project.Requires[installed.Name]; exists {
+			constraint, _ := toolbox.ParseConstraint(requirement)
+			if constraint.Match(installed.Version) {
+				return true
+			}
+		}
+
+		for _, flow := range project.Flows {
+			for _, ref := range flow.Tools {
+				name, version := toolbox.ParseRef(ref)
+				if name != installed.Name {
+					continue
+				}
+
+				if version == "" {
+					_, pinned := project.Tools[name]
+					if !pinned && cache.Contains(installed, tb[name].Path) {
+						return true
+					}
+				} else if toolbox.MatchVersion(version, installed.Version) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package tool
+
+import (
+	"fmt"
+
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/spf13/cobra"
+)
+
+var cmdToolRefresh = &cobra.Command{
+	Use:   "refresh [name...]",
+	Short: "Re-resolve tools through PATH",
+	Long: `Re-resolve the default for each tool through PATH, registering its current
+version. Versioned entries whose files no longer exist are removed. Tools that
+can no longer be found on PATH are left unchanged.`,
+	RunE: cmdToolRefreshImpl,
+}
+
+func cmdToolRefreshImpl(cmd *cobra.Command, args []string) error {
+	provider, tb, err := loadToolbox()
+	if err != nil {
+		return err
+	}
+
+	names := args
+	if len(names) == 0 {
+		for _, entry := range tb.Entries() {
+			if entry.Default {
+				names = append(names, entry.Name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		previous := tb[name].Path
+
+		if err := tb.FindAndAdd(name); err != nil {
+			fmt.Printf("Kept $%s at path '%s': %s\n", name, previous, err)
+		} else if tb[name].Path != previous {
+			fmt.Printf("Moved $%s from '%s' to '%s'\n", name, previous, tb[name].Path)
+		}
+
+		for _, version := range tb.Versions(name) {
+			ref := toolbox.Ref(name, version)
+			if err := toolbox.CheckExecutable(tb[ref].Path); err != nil {
+				delete(tb, ref)
+				fmt.Printf("Removed $%s: %s\n", ref, err)
+			}
+		}
+	}
+
+	return saveToolbox(provider, tb)
+}
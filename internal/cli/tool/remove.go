@@ -0,0 +1,37 @@
+package tool
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var cmdToolRemove = &cobra.Command{
+	Use:     "remove <name[@version]>...",
+	Aliases: []string{"rm"},
+	Short:   "Unregister tools",
+	Long: `Unregister tools from the toolbox. A name without a version removes the
+default and every registered version of the tool.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: cmdToolRemoveImpl,
+}
+
+func cmdToolRemoveImpl(cmd *cobra.Command, args []string) error {
+	provider, tb, err := loadToolbox()
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range args {
+		removed := tb.Remove(ref)
+		if len(removed) == 0 {
+			return fmt.Errorf("remove tool '%s': not registered", ref)
+		}
+
+		for _, existing := range removed {
+			fmt.Printf("Removed $%s\n", existing)
+		}
+	}
+
+	return saveToolbox(provider, tb)
+}
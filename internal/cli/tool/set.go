@@ -0,0 +1,60 @@
+package tool
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/spf13/cobra"
+)
+
+var cmdToolSet = &cobra.Command{
+	Use:   "set <name[@version]> <path>",
+	Short: "Register a tool, or one version of a tool, at a path",
+	Long: `Register the executable at a path as a tool, which is useful for tools
+that aren't on PATH. Without a version, the path becomes the default for the tool.
+With one, only that version is registered, and it only becomes the default if the
+tool has no default yet.
+
+A tool can also act as an alias with default arguments and environment, e.g.
+  ilo tool set gotest /usr/local/go/bin/go --arg test --arg -race --env GOFLAGS=-count=1`,
+	Args: cobra.ExactArgs(2),
+	RunE: cmdToolSetImpl,
+}
+
+var (
+	setArgs []string
+	setEnv  []string
+	setDir  string
+)
+
+func init() {
+	cmdToolSet.Flags().StringArrayVar(&setArgs, "arg", nil, "default argument passed before a step's arguments (repeatable)")
+	cmdToolSet.Flags().StringArrayVar(&setEnv, "env", nil, "environment variable set for the tool, as KEY=VALUE (repeatable)")
+	cmdToolSet.Flags().StringVar(&setDir, "dir", "", "working directory for the tool, relative to the flow directory")
+}
+
+func cmdToolSetImpl(cmd *cobra.Command, args []string) error {
+	provider, tb, err := loadToolbox()
+	if err != nil {
+		return err
+	}
+
+	tool := toolbox.Tool{Path: args[1], Args: setArgs, Dir: setDir}
+	for _, entry := range setEnv {
+		key, value, found := strings.Cut(entry, "=")
+		if !found || key == "" {
+			return fmt.Errorf("set tool '%s': invalid environment variable '%s'", args[0], entry)
+		}
+		if tool.Env == nil {
+			tool.Env = make(map[string]string)
+		}
+		tool.Env[key] = value
+	}
+
+	if err := addToolAtPath(tb, args[0], tool, true); err != nil {
+		return err
+	}
+
+	return saveToolbox(provider, tb)
+}
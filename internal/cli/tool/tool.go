@@ -1,13 +1,44 @@
 package tool
 
 import (
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/data/toolbox"
 	"github.com/spf13/cobra"
 )
 
 var CmdTool = &cobra.Command{
-	Use: "tool",
+	Use:   "tool",
+	Short: "Manage the tools available to flows",
 }
 
 func init() {
 	CmdTool.AddCommand(cmdToolAdd)
+	CmdTool.AddCommand(cmdToolList)
+	CmdTool.AddCommand(cmdToolRemove)
+	CmdTool.AddCommand(cmdToolSet)
+	CmdTool.AddCommand(cmdToolVerify)
+	CmdTool.AddCommand(cmdToolRefresh)
+	CmdTool.AddCommand(cmdToolInstall)
+	CmdTool.AddCommand(cmdToolPrune)
+}
+
+// loadToolbox loads the user toolbox, creating an empty one if none
+// has been saved yet.
+func loadToolbox() (provide.Provider[toolbox.Toolbox], toolbox.Toolbox, error) {
+	provider := provide.NewConfigProvider[toolbox.Toolbox]()
+	tb, err := provider.Load("toolbox",
+		provide.YamlUnmarshal[toolbox.Toolbox])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if *tb == nil {
+		// We want to be able to update the toolbox
+		*tb = make(toolbox.Toolbox)
+	}
+	return provider, *tb, nil
+}
+
+func saveToolbox(provider provide.Provider[toolbox.Toolbox], tb toolbox.Toolbox) error {
+	return provider.Save("toolbox", &tb, provide.YamlMarshal)
 }
@@ -0,0 +1,59 @@
+package tool
+
+import (
+	"fmt"
+
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/spf13/cobra"
+)
+
+var cmdToolVerify = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that every registered tool still exists and has its registered version",
+	Args:  cobra.NoArgs,
+	RunE:  cmdToolVerifyImpl,
+}
+
+func cmdToolVerifyImpl(cmd *cobra.Command, args []string) error {
+	_, tb, err := loadToolbox()
+	if err != nil {
+		return err
+	}
+
+	entries := tb.Entries()
+	failures := 0
+
+	for _, entry := range entries {
+		if err := verifyTool(entry); err != nil {
+			failures += 1
+			fmt.Printf("FAIL $%s: %s\n", entry.Ref, err)
+		} else {
+			fmt.Printf("ok   $%s\n", entry.Ref)
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("verify tools: %d of %d tools failed verification", failures, len(entries))
+	}
+	return nil
+}
+
+func verifyTool(entry toolbox.Entry) error {
+	if err := toolbox.CheckExecutable(entry.Path); err != nil {
+		return err
+	}
+
+	if entry.Version == "" {
+		return nil
+	}
+
+	version, err := toolbox.DetectVersion(entry.Name, entry.Path)
+	if err != nil {
+		return err
+	}
+
+	if !toolbox.MatchVersion(entry.Version, version) {
+		return fmt.Errorf("registered as version %s, but reports version %s", entry.Version, version)
+	}
+	return nil
+}
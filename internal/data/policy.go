@@ -0,0 +1,53 @@
+package data
+
+import "fmt"
+
+// CatchUp is what a schedule does about runs it missed, such as while the
+// server was stopped or the machine was asleep.
+type CatchUp string
+
+const (
+	// CatchUpOnce runs a flow once for any number of missed runs.
+	CatchUpOnce CatchUp = "once"
+	// CatchUpAll runs a flow once for every missed run.
+	CatchUpAll CatchUp = "all"
+	// CatchUpSkip ignores missed runs, waiting until the flow is next due.
+	CatchUpSkip CatchUp = "skip"
+)
+
+// Overlap is what a schedule does when a flow is due while its previous
+// run is still going.
+type Overlap string
+
+const (
+	// OverlapAllow starts another run alongside the previous one.
+	OverlapAllow Overlap = "allow"
+	// OverlapSkip doesn't start another run.
+	OverlapSkip Overlap = "skip"
+	// OverlapQueue starts another run once the previous one finishes.
+	OverlapQueue Overlap = "queue"
+	// OverlapCancel cancels the previous run and starts another.
+	OverlapCancel Overlap = "cancel"
+)
+
+// ParseCatchUp parses a catch-up policy, which is once if empty.
+func ParseCatchUp(policy string) (CatchUp, error) {
+	switch CatchUp(policy) {
+	case "":
+		return CatchUpOnce, nil
+	case CatchUpOnce, CatchUpAll, CatchUpSkip:
+		return CatchUp(policy), nil
+	}
+	return "", fmt.Errorf("unknown catch-up policy '%s', expected once, all or skip", policy)
+}
+
+// ParseOverlap parses an overlap policy, which is skip if empty.
+func ParseOverlap(policy string) (Overlap, error) {
+	switch Overlap(policy) {
+	case "":
+		return OverlapSkip, nil
+	case OverlapAllow, OverlapSkip, OverlapQueue, OverlapCancel:
+		return Overlap(policy), nil
+	}
+	return "", fmt.Errorf("unknown overlap policy '%s', expected allow, skip, queue or cancel", policy)
+}
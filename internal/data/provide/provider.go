@@ -31,7 +31,8 @@ type Provider[T any] interface {
 func YamlUnmarshal[T any](reader io.Reader) (*T, error) {
 	var data T
 
-	if err := yaml.NewDecoder(reader).Decode(&data); err != nil {
+	// An empty document decodes to the zero value
+	if err := yaml.NewDecoder(reader).Decode(&data); err != nil && !errors.Is(err, io.EOF) {
 		return nil, err
 	}
 
@@ -67,10 +68,6 @@ func (p fileProvider[T]) makePath(name string) string {
 }
 
 func (p fileProvider[T]) Load(name string, unmarshalFunc UnmarshalFunc[T]) (*T, error) {
-	if err := p.ensureBasePath(); err != nil {
-		return nil, err
-	}
-
 	reader, err := os.Open(p.makePath(name))
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
@@ -91,7 +88,7 @@ func (p fileProvider[T]) Save(name string, value *T, marshalFunc MarshalFunc[T])
 
 	writer, err := os.OpenFile(
 		p.makePath(name),
-		os.O_CREATE|os.O_WRONLY,
+		os.O_CREATE|os.O_WRONLY|os.O_TRUNC,
 		os.ModePerm)
 	if err != nil {
 		return err
@@ -2,21 +2,348 @@ package data
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// Schedule is a cron expression, evaluated in a time zone. Expressions have
+// five fields (minute, hour, day of month, month and day of week) or six,
+// with seconds first, and can use lists, ranges, steps and names, or one of
+// the macros @yearly, @monthly, @weekly, @daily and @hourly.
 type Schedule struct {
-	Minute int
-	Hour   int
-	Day    time.Weekday
+	expr     string
+	location *time.Location
+
+	seconds, minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday are set when those fields are unrestricted, as
+	// a day matches if either restricted field matches, like cron.
+	anyDay, anyWeekday bool
+	// fixedTime is set when the second, minute and hour fields don't start
+	// with a wildcard, which changes how daylight saving changes are
+	// handled, like cron.
+	fixedTime bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	secondField = cronField{name: "second", min: 0, max: 59}
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	dayField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Sunday can be written as 0 or 7
+	weekdayField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
 }
 
+// ParseSchedule parses a cron expression, to be evaluated in the IANA time
+// zone named by zone, or the local time zone if zone is empty.
+func ParseSchedule(expr string, zone string) (Schedule, error) {
+	s := Schedule{expr: strings.TrimSpace(expr), location: time.Local}
+	if zone != "" {
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("parse schedule '%s': unknown time zone '%s'", expr, zone)
+		}
+		s.location = location
+	}
+
+	text := s.expr
+	if strings.HasPrefix(text, "@") {
+		macro, exists := cronMacros[strings.ToLower(text)]
+		if !exists {
+			return Schedule{}, fmt.Errorf("parse schedule '%s': unknown macro, expected one of @yearly, @monthly, @weekly, @daily or @hourly", expr)
+		}
+		text = macro
+	}
+
+	fields := strings.Fields(text)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return Schedule{}, fmt.Errorf("parse schedule '%s': expected 5 fields (minute hour day month weekday) or 6 with seconds first, got %d", expr, len(fields))
+	}
+
+	var err error
+	parse := func(text string, field cronField, bits *uint64) {
+		if err == nil {
+			*bits, err = field.parse(text)
+			if err != nil {
+				err = fmt.Errorf("parse schedule '%s': %w", expr, err)
+			}
+		}
+	}
+	parse(fields[0], secondField, &s.seconds)
+	parse(fields[1], minuteField, &s.minutes)
+	parse(fields[2], hourField, &s.hours)
+	parse(fields[3], dayField, &s.days)
+	parse(fields[4], monthField, &s.months)
+	parse(fields[5], weekdayField, &s.weekdays)
+	if err != nil {
+		return Schedule{}, err
+	}
+
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays |= 1
+	}
+	s.anyDay = fields[3] == "*" || fields[3] == "?"
+	s.anyWeekday = fields[5] == "*" || fields[5] == "?"
+	s.fixedTime = true
+	for _, field := range fields[:3] {
+		if strings.HasPrefix(field, "*") || strings.HasPrefix(field, "?") {
+			s.fixedTime = false
+		}
+	}
+	return s, nil
+}
+
+// parse reads a field of a cron expression into a set of bits, one for
+// each value the field matches.
+func (f cronField) parse(text string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(text, ",") {
+		rangeText, stepText, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepText)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("%s field '%s': invalid step '%s'", f.name, text, stepText)
+			}
+		}
+
+		var low, high int
+		switch {
+		case rangeText == "*" || rangeText == "?":
+			low, high = f.min, f.max
+			if f.name == weekdayField.name {
+				high = 6
+			}
+		case strings.Contains(rangeText, "-"):
+			lowText, highText, _ := strings.Cut(rangeText, "-")
+			var err error
+			if low, err = f.value(lowText, text); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(highText, text); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("%s field '%s': range %s is backwards", f.name, text, rangeText)
+			}
+		default:
+			var err error
+			if low, err = f.value(rangeText, text); err != nil {
+				return 0, err
+			}
+			high = low
+			if hasStep {
+				// A start with a step, like 5/15, runs to the end of the range
+				high = f.max
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func (f cronField) value(text string, field string) (int, error) {
+	if value, exists := f.names[strings.ToLower(text)]; exists {
+		return value, nil
+	}
+
+	value, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("%s field '%s': invalid value '%s'", f.name, field, text)
+	}
+	if value < f.min || value > f.max {
+		return 0, fmt.Errorf("%s field '%s': value %d out of range %d-%d", f.name, field, value, f.min, f.max)
+	}
+	return value, nil
+}
+
+// String returns the expression the schedule was parsed from.
 func (s Schedule) String() string {
-	return fmt.Sprintf("M%d H%d D%s", s.Minute, s.Hour, s.Day)
+	return s.expr
+}
+
+// Location returns the time zone the schedule is evaluated in.
+func (s Schedule) Location() *time.Location {
+	if s.location == nil {
+		return time.Local
+	}
+	return s.location
 }
 
-func (s Schedule) Match(time time.Time) bool {
-	return (s.Day == -1 || s.Day == time.Weekday()) &&
-		(s.Hour == -1 || s.Hour == time.Hour()) &&
-		(s.Minute == -1 || s.Minute == time.Minute())
+func has(bits uint64, value int) bool {
+	return bits&(1<<value) != 0
+}
+
+func (s Schedule) matchDay(t time.Time) bool {
+	day := has(s.days, t.Day())
+	weekday := has(s.weekdays, int(t.Weekday()))
+
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Match reports whether the schedule runs at the second of t.
+func (s Schedule) Match(t time.Time) bool {
+	t = t.In(s.Location())
+	return has(s.seconds, t.Second()) && has(s.minutes, t.Minute()) && has(s.hours, t.Hour()) &&
+		has(s.months, int(t.Month())) && s.matchDay(t)
+}
+
+// maxSearchYears bounds the search for the next run, for schedules which
+// never run, like 30 February.
+const maxSearchYears = 5
+
+// NextAfter returns the first time after t that the schedule runs, or the
+// zero time if it never does. Daylight saving changes are handled like
+// cron: a schedule whose second, minute and hour fields start with a
+// wildcard, such as @hourly or */15 * * * *, runs at every matching time
+// the clocks show, so runs twice as often while a repeated hour passes and
+// not at all in a skipped one. Other schedules run at a fixed time of day,
+// so a time repeated when clocks go back only runs the first time, and a
+// time skipped when clocks go forward runs once they have changed.
+func (s Schedule) NextAfter(t time.Time) time.Time {
+	if !s.fixedTime {
+		return s.nextInstant(t)
+	}
+
+	loc := s.Location()
+	wall := wallClock(t.In(loc))
+	limit := wall.AddDate(maxSearchYears, 0, 0)
+
+	for {
+		if wall = s.nextWall(wall, limit); wall.IsZero() {
+			return time.Time{}
+		}
+		// An earlier time may be found for a repeated time that has already run
+		if due := wallInstant(wall, loc); due.After(t) {
+			return due
+		}
+	}
+}
+
+// nextInstant returns the first time after t at which the clocks show a
+// time that matches the schedule, or the zero time if there is none.
+func (s Schedule) nextInstant(t time.Time) time.Time {
+	loc := s.Location()
+	limit := t.AddDate(maxSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		local := t.In(loc)
+		_, offset := local.Zone()
+		_, end := local.ZoneBounds()
+
+		wall := s.nextWall(wallClock(local), wallClock(limit.In(loc)))
+		if wall.IsZero() {
+			return time.Time{}
+		}
+
+		// Wall clock times follow real time until the offset changes
+		due := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if end.IsZero() || due.Before(end) {
+			return due
+		}
+		if s.Match(end) {
+			return end.In(loc)
+		}
+		t = end
+	}
+	return time.Time{}
+}
+
+// nextWall returns the first wall clock time after wall that matches the
+// schedule, or the zero time if there is none before limit. Wall clock times
+// are given in UTC, so they are free of daylight saving changes.
+func (s Schedule) nextWall(wall time.Time, limit time.Time) time.Time {
+	t := wall.Truncate(time.Second).Add(time.Second)
+
+	for t.Before(limit) {
+		if !has(s.months, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.hours, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.minutes, t.Minute()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, time.UTC)
+			continue
+		}
+		if !has(s.seconds, t.Second()) {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// wallClock returns the wall clock time shown by t, in UTC.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// wallInstant returns the earliest time at which the clocks of loc show
+// wall. A wall clock time skipped by a daylight saving change is taken to
+// be the moment the clocks changed.
+func wallInstant(wall time.Time, loc *time.Location) time.Time {
+	// The offsets either side of wall cover any change of offset at wall
+	var earliest time.Time
+	for _, near := range []time.Time{wall.Add(-24 * time.Hour), wall.Add(24 * time.Hour)} {
+		_, offset := near.In(loc).Zone()
+		instant := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if wallClock(instant).Equal(wall) && (earliest.IsZero() || instant.Before(earliest)) {
+			earliest = instant
+		}
+	}
+	if !earliest.IsZero() {
+		return earliest
+	}
+
+	_, offset := wall.Add(-24 * time.Hour).In(loc).Zone()
+	changed, _ := wall.Add(-time.Duration(offset) * time.Second).In(loc).ZoneBounds()
+	return changed
 }
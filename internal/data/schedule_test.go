@@ -0,0 +1,99 @@
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	var tests = []struct {
+		expr string
+		zone string
+		err  string
+	}{
+		{"* * * *", "", "expected 5 fields"},
+		{"61 * * * *", "", "minute field '61': value 61 out of range 0-59"},
+		{"* * * foo *", "", "month field 'foo': invalid value 'foo'"},
+		{"*/0 * * * *", "", "invalid step '0'"},
+		{"0 20-10 * * *", "", "range 20-10 is backwards"},
+		{"@fortnightly", "", "unknown macro"},
+		{"@daily", "Mars/Olympus_Mons", "unknown time zone 'Mars/Olympus_Mons'"},
+	}
+
+	for _, tc := range tests {
+		_, err := ParseSchedule(tc.expr, tc.zone)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("ParseSchedule(%q) got: %v, want: error containing %q", tc.expr, err, tc.err)
+		}
+	}
+}
+
+func TestScheduleNextAfter(t *testing.T) {
+	var at = func(text string) time.Time {
+		parsed, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	var tests = []struct {
+		expr     string
+		zone     string
+		after    string
+		expected string
+	}{
+		{"@hourly", "UTC", "2024-03-01T10:00:00Z", "2024-03-01T11:00:00Z"},
+		{"*/15 9-17 * * mon-fri", "UTC", "2024-03-01T17:50:00Z", "2024-03-04T09:00:00Z"},
+		{"0 0 12 1,15 * *", "UTC", "2024-03-01T12:00:00Z", "2024-03-15T12:00:00Z"},
+		{"30 */20 * * * *", "UTC", "2024-03-01T10:20:31Z", "2024-03-01T10:40:30Z"},
+		// Day of month and day of week match either, like cron
+		{"0 0 13 * fri", "UTC", "2024-09-01T00:00:00Z", "2024-09-06T00:00:00Z"},
+		{"0 0 29 feb *", "UTC", "2024-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"0 0 30 feb *", "UTC", "2024-03-01T00:00:00Z", ""},
+		{"@daily", "Asia/Kolkata", "2024-03-01T00:00:00Z", "2024-03-01T18:30:00Z"},
+		{"0 9 * * *", "Europe/London", "2024-03-30T12:00:00Z", "2024-03-31T08:00:00Z"},
+		{"0 9 * * sun", "America/New_York", "2024-11-01T00:00:00Z", "2024-11-03T14:00:00Z"},
+		// 01:30 happens twice as clocks go back, and only runs the first time
+		{"30 1 * * *", "America/New_York", "2024-11-03T04:00:00Z", "2024-11-03T05:30:00Z"},
+		{"30 1 * * *", "America/New_York", "2024-11-03T05:30:00Z", "2024-11-04T06:30:00Z"},
+		{"30 1 * * *", "America/New_York", "2024-11-03T06:00:00Z", "2024-11-04T06:30:00Z"},
+		// Schedules with a wildcard hour or minute run as the clocks show
+		// their times, so also run in the repeated hour
+		{"0 * * * *", "America/New_York", "2024-11-03T05:00:00Z", "2024-11-03T06:00:00Z"},
+		{"0 * * * *", "America/New_York", "2024-11-03T06:00:00Z", "2024-11-03T07:00:00Z"},
+		{"*/15 * * * *", "America/New_York", "2024-11-03T05:45:00Z", "2024-11-03T06:00:00Z"},
+		{"*/15 1 * * *", "America/New_York", "2024-11-03T05:45:00Z", "2024-11-03T06:00:00Z"},
+		{"*/15 1 * * *", "America/New_York", "2024-11-03T06:45:00Z", "2024-11-04T06:00:00Z"},
+		// 02:30 is skipped as clocks go forward, so runs once they have changed
+		{"30 2 * * *", "America/New_York", "2024-03-10T05:00:00Z", "2024-03-10T07:00:00Z"},
+		{"30 2 * * *", "America/New_York", "2024-03-10T07:00:00Z", "2024-03-11T06:30:00Z"},
+		// and don't run in the skipped hour
+		{"*/15 2 * * *", "America/New_York", "2024-03-10T05:00:00Z", "2024-03-11T06:00:00Z"},
+		{"0 * * * *", "America/New_York", "2024-03-10T06:00:00Z", "2024-03-10T07:00:00Z"},
+		{"30 3 * * *", "America/New_York", "2024-03-10T05:00:00Z", "2024-03-10T07:30:00Z"},
+	}
+
+	for _, tc := range tests {
+		schedule, err := ParseSchedule(tc.expr, tc.zone)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q) got: %v, want: nil", tc.expr, err)
+		}
+
+		got := schedule.NextAfter(at(tc.after))
+		if tc.expected == "" {
+			if !got.IsZero() {
+				t.Fatalf("%q after %s got: %s, want: never", tc.expr, tc.after, got)
+			}
+			continue
+		}
+		if !got.Equal(at(tc.expected)) {
+			t.Fatalf("%q after %s got: %s, want: %s", tc.expr, tc.after, got.UTC(), tc.expected)
+		}
+		// Times skipped by daylight saving changes run when the clocks change
+		if changed, _ := got.ZoneBounds(); !schedule.Match(got) && !got.Equal(changed) {
+			t.Fatalf("%q got: no match at %s, want: match", tc.expr, got)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package toolbox
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/fourls/ilo/internal/data/provide"
+)
+
+// Sources of the layers a toolbox can be assembled from.
+const (
+	SourceProject = "project"
+	SourceUser    = "user"
+	SourceSystem  = "system"
+)
+
+// SystemDir returns the directory holding the system-wide ilo configuration.
+func SystemDir() string {
+	if runtime.GOOS == "windows" {
+		programData := os.Getenv("ProgramData")
+		if programData == "" {
+			programData = `C:\ProgramData`
+		}
+		return filepath.Join(programData, "ilo")
+	}
+	return "/etc/ilo"
+}
+
+// Merge combines toolbox layers, given in order of precedence, so that each
+// reference resolves to the tool from the first layer that registers it.
+func Merge(layers ...Toolbox) Toolbox {
+	merged := make(Toolbox)
+	for i := len(layers) - 1; i >= 0; i-- {
+		for ref, tool := range layers[i] {
+			merged[ref] = tool
+		}
+	}
+	return merged
+}
+
+// LoadLayered loads the toolbox a project sees: the project's own tools,
+// layered over the user toolbox from loader, layered over the system-wide
+// toolbox. The project tools come from the tools declared inline in its
+// definition and from a .ilo/toolbox.yml next to it, with inline tools
+// taking precedence. If projectDir is empty, only the user and system
+// toolboxes are loaded.
+func LoadLayered(loader provide.Loader[Toolbox], projectDir string, inline Toolbox) (Toolbox, error) {
+	return loadLayers(provide.NewFileProvider[Toolbox](SystemDir()), loader, projectDir, inline)
+}
+
+func loadLayers(systemLoader provide.Loader[Toolbox], userLoader provide.Loader[Toolbox], projectDir string, inline Toolbox) (Toolbox, error) {
+	system, err := loadLayer(systemLoader, SourceSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := loadLayer(userLoader, SourceUser)
+	if err != nil {
+		return nil, err
+	}
+
+	if projectDir == "" {
+		return Merge(user, system), nil
+	}
+
+	project, err := LoadProject(projectDir, inline)
+	if err != nil {
+		return nil, err
+	}
+	return Merge(project, user, system), nil
+}
+
+// LoadProject loads the project layer of a toolbox, resolving relative tool
+// paths against the project directory.
+func LoadProject(projectDir string, inline Toolbox) (Toolbox, error) {
+	file, err := loadLayer(
+		provide.NewFileProvider[Toolbox](filepath.Join(projectDir, ".ilo")),
+		SourceProject)
+	if err != nil {
+		return nil, err
+	}
+
+	project := Merge(inline, file)
+	for ref, tool := range project {
+		if !filepath.IsAbs(tool.Path) && strings.ContainsAny(tool.Path, `/\`) {
+			tool.Path = filepath.Join(projectDir, tool.Path)
+		}
+		tool.Source = SourceProject
+		project[ref] = tool
+	}
+	return project, nil
+}
+
+func loadLayer(loader provide.Loader[Toolbox], source string) (Toolbox, error) {
+	tb, err := loader.Load("toolbox", provide.YamlUnmarshal[Toolbox])
+	if err != nil {
+		return nil, err
+	}
+
+	for ref, tool := range *tb {
+		tool.Source = source
+		(*tb)[ref] = tool
+	}
+	return *tb, nil
+}
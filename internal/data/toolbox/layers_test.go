@@ -0,0 +1,77 @@
+package toolbox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fourls/ilo/internal/data/provide"
+)
+
+func TestMerge(t *testing.T) {
+	var merged = Merge(
+		Toolbox{"go": {Path: "/project/go"}},
+		Toolbox{"go": {Path: "/user/go"}, "node": {Path: "/user/node"}},
+		Toolbox{"node": {Path: "/system/node"}, "make": {Path: "/system/make"}},
+	)
+
+	var expected = Toolbox{
+		"go":   {Path: "/project/go"},
+		"node": {Path: "/user/node"},
+		"make": {Path: "/system/make"},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("got: %v, want: %v", merged, expected)
+	}
+}
+
+func TestLoadLayers(t *testing.T) {
+	var dir = t.TempDir()
+	var write = func(path string, data string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var systemDir = filepath.Join(dir, "system")
+	var userDir = filepath.Join(dir, "user")
+	var projectDir = filepath.Join(dir, "project")
+	write(filepath.Join(systemDir, "toolbox.yml"), "go: /system/go\nnode: /system/node\nmake: /system/make\n")
+	write(filepath.Join(userDir, "toolbox.yml"), "go: /user/go\nnode: /user/node\n")
+	write(filepath.Join(projectDir, ".ilo", "toolbox.yml"), "go: /file/go\nnpm: ./bin/npm\n")
+	var inline = Toolbox{"go": {Path: "/inline/go"}}
+
+	tb, err := loadLayers(
+		provide.NewFileProvider[Toolbox](systemDir),
+		provide.NewFileProvider[Toolbox](userDir),
+		projectDir, inline)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var expected = Toolbox{
+		"go":   {Path: "/inline/go", Source: SourceProject},
+		"npm":  {Path: filepath.Join(projectDir, "bin", "npm"), Source: SourceProject},
+		"node": {Path: "/user/node", Source: SourceUser},
+		"make": {Path: "/system/make", Source: SourceSystem},
+	}
+	if !reflect.DeepEqual(tb, expected) {
+		t.Fatalf("got: %v, want: %v", tb, expected)
+	}
+
+	// Without a project, only the user and system toolboxes are used
+	tb, err = loadLayers(
+		provide.NewFileProvider[Toolbox](systemDir),
+		provide.NewFileProvider[Toolbox](userDir),
+		"", inline)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+	if tb["go"].Path != "/user/go" || tb["go"].Source != SourceUser {
+		t.Fatalf("got: %v, want: go from the user toolbox", tb["go"])
+	}
+}
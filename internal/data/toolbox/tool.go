@@ -0,0 +1,55 @@
+package toolbox
+
+import (
+	"maps"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Tool describes how to invoke a registered tool. Besides the path of its
+// executable, a tool can act as an alias, supplying default arguments,
+// environment variables and a working directory whenever it is used.
+type Tool struct {
+	Path    string            `yaml:"path"`
+	Version string            `yaml:"version,omitempty"`
+	Args    []string          `yaml:"args,omitempty"`
+	Env     map[string]string `yaml:"env,omitempty"`
+	// Dir is the working directory of the tool. A relative directory is
+	// resolved against the directory of the flow.
+	Dir string `yaml:"dir,omitempty"`
+	// Source is the layer the tool was loaded from, see LoadLayered.
+	Source string `yaml:"-"`
+}
+
+// toolFields has the same fields as Tool, without its YAML methods.
+type toolFields Tool
+
+// UnmarshalYAML decodes a tool from either a mapping of its fields or,
+// for toolboxes saved before tools had any other fields, a plain path.
+func (t *Tool) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = Tool{Path: node.Value}
+		return nil
+	}
+	return node.Decode((*toolFields)(t))
+}
+
+// MarshalYAML encodes a tool that only has a path as a plain path, and
+// any other tool as a mapping of its fields.
+func (t Tool) MarshalYAML() (any, error) {
+	if t.Version == "" && len(t.Args) == 0 && len(t.Env) == 0 && t.Dir == "" {
+		return t.Path, nil
+	}
+	return toolFields(t), nil
+}
+
+// Environ returns the tool's environment variables as KEY=value strings,
+// sorted by key.
+func (t Tool) Environ() []string {
+	env := make([]string, 0, len(t.Env))
+	for _, key := range slices.Sorted(maps.Keys(t.Env)) {
+		env = append(env, key+"="+t.Env[key])
+	}
+	return env
+}
@@ -2,15 +2,107 @@ package toolbox
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 
 	"github.com/hairyhenderson/go-which"
 )
 
-type Toolbox map[string]string
+// Toolbox maps tool references to tools.
+// A reference is either a bare tool name (e.g. go), which refers to the
+// default version of that tool, or a name and version separated by '@'
+// (e.g. go@1.22).
+type Toolbox map[string]Tool
 
-func (t Toolbox) FindAndAdd(name string) error {
+// ParseRef splits a tool reference into its name and version.
+// The version is empty if the reference does not specify one.
+func ParseRef(ref string) (name string, version string) {
+	name, version, _ = strings.Cut(ref, "@")
+	return name, version
+}
+
+// Ref builds a tool reference from a name and an optional version.
+func Ref(name string, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+// Add registers a tool under its version, if it has one.
+// The tool also becomes the default for its name if there is no default yet.
+func (t Toolbox) Add(name string, tool Tool) {
+	if _, exists := t[name]; !exists || tool.Version == "" {
+		t[name] = tool
+	}
+	if tool.Version != "" {
+		t[Ref(name, tool.Version)] = tool
+	}
+}
+
+// Versions returns every registered version of a tool, oldest first.
+func (t Toolbox) Versions(name string) []string {
+	var versions []string
+	for ref := range t {
+		refName, version := ParseRef(ref)
+		if refName == name && version != "" {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return CompareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// Find returns the tool registered for a name and version. An empty version finds the default for
+// the tool, otherwise the exact version is preferred, falling back to the
+// newest registered version that matches it (e.g. 1.22 matches 1.22.5).
+func (t Toolbox) Find(name string, version string) (Tool, error) {
+	entry, err := t.Resolve(name, version)
+	return entry.Tool, err
+}
+
+// Resolve finds a tool in the same way as Find, returning the entry that
+// was found.
+func (t Toolbox) Resolve(name string, version string) (Entry, error) {
+	if version == "" {
+		if tool, exists := t[name]; exists {
+			tool.Version = t.DefaultVersion(name)
+			return Entry{Ref: name, Name: name, Default: true, Tool: tool}, nil
+		}
+		return Entry{}, fmt.Errorf("tool '%s' is not registered", name)
+	}
+
+	versions := t.Versions(name)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i] == version {
+			versions = versions[i : i+1]
+			break
+		}
+	}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		if MatchVersion(version, versions[i]) {
+			ref := Ref(name, versions[i])
+			tool := t[ref]
+			tool.Version = versions[i]
+			return Entry{Ref: ref, Name: name, Tool: tool}, nil
+		}
+	}
+
+	return Entry{}, fmt.Errorf("no version of tool '%s' matching '%s' is registered", name, version)
+}
+
+// FindAndAdd searches PATH for a tool and registers it, along with its
+// detected version. If ref specifies a version, the tool found on PATH must
+// match that version.
+func (t Toolbox) FindAndAdd(ref string) error {
+	name, wantVersion := ParseRef(ref)
 	programName := name
 
 	if runtime.GOOS == "windows" && filepath.Ext(name) == "" {
@@ -22,6 +114,123 @@ func (t Toolbox) FindAndAdd(name string) error {
 		return fmt.Errorf("add tool '%s': could not find on PATH", name)
 	}
 
-	t[name] = path
+	version, err := DetectVersion(name, path)
+	if err != nil && wantVersion != "" {
+		return fmt.Errorf("add tool '%s': %w", ref, err)
+	}
+
+	if wantVersion != "" && !MatchVersion(wantVersion, version) {
+		return fmt.Errorf(
+			"add tool '%s': found version %s at '%s'",
+			ref, version, path)
+	}
+
+	// Keep any arguments, environment or directory already configured
+	tool := t[name]
+	tool.Path = path
+	tool.Version = version
+	t[name] = tool
+
+	if version != "" {
+		versioned := t[Ref(name, version)]
+		versioned.Path = path
+		versioned.Version = version
+		t[Ref(name, version)] = versioned
+	}
+	return nil
+}
+
+// Entry describes a single registered tool reference.
+type Entry struct {
+	Ref     string
+	Name    string
+	Default bool
+	Tool
+}
+
+// Entries returns every registered tool reference, ordered by name and
+// then by version, with the default for each tool first.
+func (t Toolbox) Entries() []Entry {
+	entries := make([]Entry, 0, len(t))
+	for ref, tool := range t {
+		name, version := ParseRef(ref)
+		entry := Entry{Ref: ref, Name: name, Tool: tool}
+		if version == "" {
+			entry.Default = true
+			entry.Version = t.DefaultVersion(name)
+		} else {
+			entry.Version = version
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.Name != b.Name:
+			return a.Name < b.Name
+		case a.Default != b.Default:
+			return a.Default
+		default:
+			return CompareVersions(a.Version, b.Version) < 0
+		}
+	})
+	return entries
+}
+
+// DefaultVersion returns the version of the default for a tool, or an empty
+// string if it is unknown. Defaults registered without a version take the
+// version of the versioned reference with the same path, if any.
+func (t Toolbox) DefaultVersion(name string) string {
+	tool, exists := t[name]
+	if !exists {
+		return ""
+	}
+	if tool.Version != "" {
+		return tool.Version
+	}
+
+	versions := t.Versions(name)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if t[Ref(name, versions[i])].Path == tool.Path {
+			return versions[i]
+		}
+	}
+	return ""
+}
+
+// Remove unregisters a tool reference and returns the references removed.
+// A bare tool name removes the default and every version of the tool.
+func (t Toolbox) Remove(ref string) []string {
+	name, version := ParseRef(ref)
+
+	var removed []string
+	for existing := range t {
+		existingName, _ := ParseRef(existing)
+		if existing == ref || (version == "" && existingName == name) {
+			removed = append(removed, existing)
+			delete(t, existing)
+		}
+	}
+
+	sort.Strings(removed)
+	return removed
+}
+
+// CheckExecutable reports whether path refers to a file that can still be
+// executed.
+func CheckExecutable(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if stat.IsDir() {
+		return fmt.Errorf("'%s' is a directory", path)
+	}
+
+	if runtime.GOOS != "windows" && stat.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("'%s' is not executable", path)
+	}
 	return nil
 }
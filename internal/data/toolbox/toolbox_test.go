@@ -0,0 +1,134 @@
+package toolbox
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindVersion(t *testing.T) {
+	var tb = Toolbox{}
+	tb.Add("go", Tool{Path: "/opt/go1.21/bin/go", Version: "1.21.8"})
+	tb.Add("go", Tool{Path: "/opt/go1.22.1/bin/go", Version: "1.22.1"})
+	tb.Add("go", Tool{Path: "/opt/go1.22.10/bin/go", Version: "1.22.10"})
+
+	var tests = []struct {
+		version  string
+		expected string
+	}{
+		{"", "/opt/go1.21/bin/go"},
+		{"1.22.1", "/opt/go1.22.1/bin/go"},
+		{"1.22", "/opt/go1.22.10/bin/go"},
+		{"1", "/opt/go1.22.10/bin/go"},
+	}
+
+	for _, tc := range tests {
+		var tool, err = tb.Find("go", tc.version)
+		if err != nil || tool.Path != tc.expected {
+			t.Fatalf("got: %s, %v, want: %s, nil", tool.Path, err, tc.expected)
+		}
+	}
+
+	if _, err := tb.Find("go", "1.2"); err == nil {
+		t.Fatalf("got: nil, want: error for unregistered version")
+	}
+
+	var versions = tb.Versions("go")
+	var expected = []string{"1.21.8", "1.22.1", "1.22.10"}
+	if !reflect.DeepEqual(versions, expected) {
+		t.Fatalf("got: %v, want: %v", versions, expected)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	var tests = []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.22", "1.22", 0},
+		{"1.9", "1.10", -1},
+		{"1.22.1", "1.22", 1},
+		{"2", "1.99.99", 1},
+		{"1.0.0-rc1", "1.0.0-rc2", -1},
+	}
+
+	for _, tc := range tests {
+		if got := CompareVersions(tc.a, tc.b); got != tc.expected {
+			t.Fatalf("CompareVersions(%s, %s) got: %d, want: %d", tc.a, tc.b, got, tc.expected)
+		}
+	}
+}
+
+func TestConstraint(t *testing.T) {
+	var tests = []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{">=1.21, <2", "1.21", true},
+		{">=1.21, <2", "1.22.5", true},
+		{">=1.21, <2", "1.20.9", false},
+		{">=1.21, <2", "2.0", false},
+		{" >= 1.21 ,< 2 ", "1.9", false},
+		{"1.22", "1.22.5", true},
+		{"1.22", "1.220", false},
+		{"=1.22", "1.22.5", false},
+		{"!=1.22.1", "1.22.1", false},
+		{">1.22", "1.22.1", true},
+		{"<=1.22", "1.22", true},
+		{"<=1.22", "1.21.9", true},
+	}
+
+	for _, tc := range tests {
+		constraint, err := ParseConstraint(tc.constraint)
+		if err != nil {
+			t.Fatalf("got: %v, want: nil for '%s'", err, tc.constraint)
+		}
+		if got := constraint.Match(tc.version); got != tc.expected {
+			t.Fatalf("'%s'.Match(%s) got: %v, want: %v", tc.constraint, tc.version, got, tc.expected)
+		}
+	}
+
+	if constraint, _ := ParseConstraint(">= 1.21,<2"); constraint.String() != ">=1.21, <2" {
+		t.Fatalf("got: %s, want: >=1.21, <2", constraint)
+	}
+
+	for _, text := range []string{"", ">=", "1.21,", ">=1.21 <2", "=>1.21", ">=1.21, !"} {
+		if _, err := ParseConstraint(text); err == nil {
+			t.Fatalf("got: nil, want: error for '%s'", text)
+		}
+	}
+}
+
+func TestDecodeToolbox(t *testing.T) {
+	var data = []byte(`
+go: /usr/local/go/bin/go
+gotest:
+  path: /usr/local/go/bin/go
+  args: [test, -race, -count=1]
+  env:
+    GOFLAGS: -mod=readonly`)
+
+	var tb Toolbox
+	if err := yaml.Unmarshal(data, &tb); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var expected = Toolbox{
+		"go": {Path: "/usr/local/go/bin/go"},
+		"gotest": {
+			Path: "/usr/local/go/bin/go",
+			Args: []string{"test", "-race", "-count=1"},
+			Env:  map[string]string{"GOFLAGS": "-mod=readonly"},
+		},
+	}
+	if !reflect.DeepEqual(tb, expected) {
+		t.Fatalf("got: %v, want: %v", tb, expected)
+	}
+
+	out, err := yaml.Marshal(Toolbox{"go": {Path: "/usr/local/go/bin/go"}})
+	if err != nil || string(out) != "go: /usr/local/go/bin/go\n" {
+		t.Fatalf("got: %q, %v, want: plain path", out, err)
+	}
+}
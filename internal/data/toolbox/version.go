@@ -0,0 +1,198 @@
+package toolbox
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type versionProbe struct {
+	args    []string
+	pattern *regexp.Regexp
+}
+
+var defaultVersionProbe = versionProbe{
+	args:    []string{"--version"},
+	pattern: regexp.MustCompile(`(\d+\.\d+(?:\.\d+)*)`),
+}
+
+// versionProbes describes how to query the version of tools that don't
+// follow the `--version` convention, or whose output contains other numbers
+// before the version.
+var versionProbes = map[string]versionProbe{
+	"go": {
+		args:    []string{"version"},
+		pattern: regexp.MustCompile(`go(\d+\.\d+(?:\.\d+)*)`),
+	},
+	"bash": {
+		args:    []string{"--version"},
+		pattern: regexp.MustCompile(`version (\d+\.\d+(?:\.\d+)*)`),
+	},
+	"node": {
+		args:    []string{"--version"},
+		pattern: regexp.MustCompile(`v(\d+\.\d+(?:\.\d+)*)`),
+	},
+	"java": {
+		args:    []string{"-version"},
+		pattern: regexp.MustCompile(`version "(\d+(?:\.\d+)*)`),
+	},
+	"python": {
+		args:    []string{"--version"},
+		pattern: regexp.MustCompile(`Python (\d+\.\d+(?:\.\d+)*)`),
+	},
+	"python3": {
+		args:    []string{"--version"},
+		pattern: regexp.MustCompile(`Python (\d+\.\d+(?:\.\d+)*)`),
+	},
+}
+
+const versionProbeTimeout = 10 * time.Second
+
+// DetectVersion runs the version command of the tool at path and extracts
+// its version number from the output.
+func DetectVersion(name string, path string) (string, error) {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+
+	probe, exists := versionProbes[name]
+	if !exists {
+		probe = defaultVersionProbe
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionProbeTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, probe.args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("detect version of '%s': %w", path, err)
+	}
+
+	match := probe.pattern.FindSubmatch(out)
+	if match == nil {
+		return "", fmt.Errorf("detect version of '%s': no version in output", path)
+	}
+	return string(match[1]), nil
+}
+
+// CompareVersions compares two dotted version strings component by
+// component, returning -1, 0 or 1. Non-numeric components compare as text.
+func CompareVersions(a string, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		if i >= len(aParts) {
+			return -1
+		}
+		if i >= len(bParts) {
+			return 1
+		}
+
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aParts[i] != bParts[i]:
+			return strings.Compare(aParts[i], bParts[i])
+		}
+	}
+
+	return 0
+}
+
+// MatchVersion reports whether have satisfies want, where want may be a
+// prefix of have (e.g. 1.22 matches 1.22.5, but not 1.2 or 1.220).
+func MatchVersion(want string, have string) bool {
+	if want == "" || want == have {
+		return true
+	}
+	return strings.HasPrefix(have, want+".")
+}
+
+type versionComparison struct {
+	op      string
+	version string
+}
+
+// Constraint is a list of version comparisons that must all hold, written
+// as e.g. ">=1.21, <2". A version without an operator matches that version
+// or any version it is a prefix of.
+type Constraint []versionComparison
+
+var constraintOperators = []string{">=", "<=", "!=", ">", "<", "="}
+
+// ParseConstraint parses a comma-separated list of version comparisons.
+func ParseConstraint(text string) (Constraint, error) {
+	var constraint Constraint
+
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		comparison := versionComparison{}
+
+		for _, op := range constraintOperators {
+			if strings.HasPrefix(part, op) {
+				comparison.op = op
+				break
+			}
+		}
+
+		comparison.version = strings.TrimSpace(part[len(comparison.op):])
+		if comparison.version == "" {
+			return nil, fmt.Errorf("parse version constraint '%s': missing version", text)
+		}
+		if strings.ContainsAny(comparison.version, "<>=! ") {
+			return nil, fmt.Errorf("parse version constraint '%s': invalid version '%s'", text, comparison.version)
+		}
+
+		constraint = append(constraint, comparison)
+	}
+
+	return constraint, nil
+}
+
+// Match reports whether version satisfies every comparison in the constraint.
+func (c Constraint) Match(version string) bool {
+	for _, comparison := range c {
+		cmp := CompareVersions(version, comparison.version)
+
+		var ok bool
+		switch comparison.op {
+		case "":
+			ok = MatchVersion(comparison.version, version)
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Constraint) String() string {
+	parts := make([]string, len(c))
+	for i, comparison := range c {
+		parts[i] = comparison.op + comparison.version
+	}
+	return strings.Join(parts, ", ")
+}
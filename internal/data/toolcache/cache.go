@@ -0,0 +1,129 @@
+package toolcache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Cache is a directory of tools installed and owned by ilo, laid out as
+// <dir>/<name>/<version>/.
+type Cache struct {
+	Dir string
+}
+
+// Installed describes a version of a tool unpacked into the cache.
+type Installed struct {
+	Name    string
+	Version string
+	Dir     string
+}
+
+// NewConfigCache returns the tool cache in the user's ilo config directory.
+func NewConfigCache() Cache {
+	configPath, err := os.UserConfigDir()
+	if err != nil {
+		panic(err)
+	}
+	return Cache{Dir: filepath.Join(configPath, "ilo", "tools")}
+}
+
+// VersionDir returns the directory a version of a tool is installed into.
+func (c Cache) VersionDir(name string, version string) string {
+	return filepath.Join(c.Dir, name, version)
+}
+
+// Contains reports whether path is inside the installation of a version.
+func (c Cache) Contains(installed Installed, path string) bool {
+	rel, err := filepath.Rel(installed.Dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// List returns every tool version installed in the cache.
+func (c Cache) List() ([]Installed, error) {
+	names, err := os.ReadDir(c.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var installed []Installed
+	for _, name := range names {
+		if !name.IsDir() {
+			continue
+		}
+
+		versions, err := os.ReadDir(filepath.Join(c.Dir, name.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, version := range versions {
+			// Hidden directories are installations in progress
+			if !version.IsDir() || strings.HasPrefix(version.Name(), ".") {
+				continue
+			}
+			installed = append(installed, Installed{
+				Name:    name.Name(),
+				Version: version.Name(),
+				Dir:     c.VersionDir(name.Name(), version.Name()),
+			})
+		}
+	}
+	return installed, nil
+}
+
+// Remove deletes an installed version of a tool.
+func (c Cache) Remove(name string, version string) error {
+	if err := os.RemoveAll(c.VersionDir(name, version)); err != nil {
+		return err
+	}
+
+	// Tidy up the tool's directory once its last version is gone
+	remaining, err := os.ReadDir(filepath.Join(c.Dir, name))
+	if err == nil && len(remaining) == 0 {
+		return os.Remove(filepath.Join(c.Dir, name))
+	}
+	return nil
+}
+
+// findBinary searches an installed tree for the executable of a tool,
+// preferring the shallowest match and those inside a bin directory.
+func findBinary(root string, name string) (string, error) {
+	programName := name
+	if runtime.GOOS == "windows" && filepath.Ext(name) == "" {
+		programName = name + ".exe"
+	}
+
+	best := ""
+	bestScore := 0
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != programName {
+			return err
+		}
+
+		score := strings.Count(path, string(filepath.Separator)) * 2
+		if filepath.Base(filepath.Dir(path)) != "bin" {
+			score += 1
+		}
+
+		if best == "" || score < bestScore {
+			best, bestScore = path, score
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("no executable named '%s' found", programName)
+	}
+	return best, nil
+}
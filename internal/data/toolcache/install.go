@@ -0,0 +1,394 @@
+package toolcache
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InstallOptions controls how a tool is installed into the cache.
+type InstallOptions struct {
+	// Sha256 is the expected checksum of the archive, or of the binary when
+	// installing from an unpacked directory. If empty, the checksum is read
+	// from a <archive>.sha256 or SHA256SUMS file next to the archive or
+	// binary, and the install fails if there is none.
+	Sha256 string
+	// Binary is the path of the executable within the installed tree. If
+	// empty, the tree is searched for an executable named after the tool.
+	Binary string
+	// Force replaces an existing installation of the same version.
+	Force bool
+}
+
+var archiveExtensions = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+func isArchive(path string) bool {
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Install unpacks a version of a tool into the cache from source, which may
+// be an archive, a mirror directory holding an archive named
+// <name>-<version>.<ext>, or a directory containing the unpacked tool.
+// It returns the path of the tool's executable.
+func (c Cache) Install(name string, version string, source string, opts InstallOptions) (string, error) {
+	wrapErr := func(err error) error {
+		return fmt.Errorf("install %s@%s: %w", name, version, err)
+	}
+
+	stat, err := os.Stat(source)
+	if err != nil {
+		return "", wrapErr(err)
+	}
+
+	archive := ""
+	if !stat.IsDir() {
+		archive = source
+	} else if mirrored, err := findMirrored(source, name, version); err != nil {
+		return "", wrapErr(err)
+	} else {
+		archive = mirrored
+	}
+
+	if archive != "" {
+		if !isArchive(archive) {
+			return "", wrapErr(fmt.Errorf("'%s' is not a supported archive (%s)",
+				archive, strings.Join(archiveExtensions, ", ")))
+		}
+
+		expected := opts.Sha256
+		if expected == "" {
+			if expected, err = readChecksum(archive); err != nil {
+				return "", wrapErr(err)
+			}
+		}
+		if err := verifyChecksum(archive, archive, expected); err != nil {
+			return "", wrapErr(err)
+		}
+	}
+
+	dest := c.VersionDir(name, version)
+	if _, err := os.Stat(dest); err == nil && !opts.Force {
+		return "", wrapErr(fmt.Errorf("already installed at '%s'", dest))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return "", wrapErr(err)
+	}
+
+	// Unpack next to the destination, so a failed install leaves no trace
+	staging, err := os.MkdirTemp(filepath.Dir(dest), "."+version+"-")
+	if err != nil {
+		return "", wrapErr(err)
+	}
+	defer os.RemoveAll(staging)
+
+	switch {
+	case archive == "":
+		err = copyTree(source, staging)
+	case strings.HasSuffix(strings.ToLower(archive), ".zip"):
+		err = unzip(archive, staging)
+	default:
+		err = untar(archive, staging)
+	}
+	if err != nil {
+		return "", wrapErr(err)
+	}
+
+	binary := filepath.Join(staging, filepath.FromSlash(opts.Binary))
+	if opts.Binary == "" {
+		if binary, err = findBinary(staging, name); err != nil {
+			return "", wrapErr(err)
+		}
+	} else if _, err := os.Stat(binary); err != nil {
+		return "", wrapErr(err)
+	}
+
+	rel, _ := filepath.Rel(staging, binary)
+
+	// An unpacked tool is verified by its binary, as copied into the cache
+	if archive == "" {
+		expected := opts.Sha256
+		if expected == "" {
+			if expected, err = readChecksum(binary); err != nil {
+				return "", wrapErr(fmt.Errorf("no checksum found for '%s': provide one, or a %s.sha256 or SHA256SUMS file next to it",
+					filepath.Join(source, rel), filepath.Base(binary)))
+			}
+		}
+		if err := verifyChecksum(binary, filepath.Join(source, rel), expected); err != nil {
+			return "", wrapErr(err)
+		}
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return "", wrapErr(err)
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		return "", wrapErr(err)
+	}
+	return filepath.Join(dest, rel), nil
+}
+
+// findMirrored returns the archive for a tool version in a mirror
+// directory, or an empty string if the directory holds no such archive.
+func findMirrored(dir string, name string, version string) (string, error) {
+	for _, ext := range archiveExtensions {
+		path := filepath.Join(dir, name+"-"+version+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// readChecksum finds the expected checksum of an archive or binary, either
+// in a <file>.sha256 file or in a SHA256SUMS file in the same directory.
+func readChecksum(path string) (string, error) {
+	if data, err := os.ReadFile(path + ".sha256"); err == nil {
+		fields := strings.Fields(string(data))
+		if len(fields) > 0 {
+			return fields[0], nil
+		}
+	}
+
+	sums, err := os.Open(filepath.Join(filepath.Dir(path), "SHA256SUMS"))
+	if err == nil {
+		defer sums.Close()
+		scanner := bufio.NewScanner(sums)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == filepath.Base(path) {
+				return fields[0], nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no checksum found for '%s': provide one, or a %s.sha256 or SHA256SUMS file",
+		path, filepath.Base(path))
+}
+
+// verifyChecksum checks the SHA-256 checksum of the file at path, which is
+// called name in errors.
+func verifyChecksum(path string, name string, expected string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return fmt.Errorf("checksum mismatch for '%s': expected %s, got %s", name, expected, actual)
+	}
+	return nil
+}
+
+// safeJoin joins an archive entry name onto dest, rejecting entries that
+// would be written outside of it.
+func safeJoin(dest string, name string) (string, error) {
+	path := filepath.Join(dest, filepath.FromSlash(name))
+	rel, err := filepath.Rel(dest, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry '%s' escapes the install directory", name)
+	}
+	return path, nil
+}
+
+func writeFile(path string, reader io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func untar(archive string, dest string) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	lower := strings.ToLower(archive)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	// Entries can't be written through symlinks unpacked earlier, as these
+	// could point anywhere once followed
+	symlinks := make(map[string]bool)
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		path, err := safeJoin(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if throughSymlink(dest, path, symlinks) {
+			return fmt.Errorf("archive entry '%s' is written through a symlink", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, os.ModePerm)
+		case tar.TypeReg:
+			err = writeFile(path, tr, header.FileInfo().Mode())
+		case tar.TypeSymlink:
+			if err = checkSymlink(dest, path, header, symlinks); err == nil {
+				if err = os.MkdirAll(filepath.Dir(path), os.ModePerm); err == nil {
+					err = os.Symlink(header.Linkname, path)
+				}
+				symlinks[path] = true
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// throughSymlink reports whether path, or any directory above it within
+// dest, is one of the symlinks unpacked so far.
+func throughSymlink(dest string, path string, symlinks map[string]bool) bool {
+	for ; path != dest && path != filepath.Dir(path); path = filepath.Dir(path) {
+		if symlinks[path] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSymlink rejects a symlink entry unpacked to path unless its target
+// is relative and stays within dest.
+func checkSymlink(dest string, path string, header *tar.Header, symlinks map[string]bool) error {
+	target := filepath.FromSlash(header.Linkname)
+	if filepath.IsAbs(target) || strings.HasPrefix(header.Linkname, "/") || filepath.VolumeName(target) != "" {
+		return fmt.Errorf("archive entry '%s' links to absolute path '%s'", header.Name, header.Linkname)
+	}
+
+	resolved := filepath.Join(filepath.Dir(path), target)
+	rel, err := filepath.Rel(dest, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("archive entry '%s' links outside the install directory", header.Name)
+	}
+
+	// A target such as link/.. resolves somewhere other than it appears to,
+	// so it can't go back up once it has gone through another symlink.
+	// Going down through one is fine, as that symlink was checked itself.
+	step := filepath.Dir(path)
+	viaSymlink := false
+	for _, part := range strings.Split(target, string(filepath.Separator)) {
+		if part == ".." && viaSymlink {
+			return fmt.Errorf("archive entry '%s' links back up through another symlink", header.Name)
+		}
+		step = filepath.Join(step, part)
+		viaSymlink = viaSymlink || symlinks[step]
+	}
+	return nil
+}
+
+func unzip(archive string, dest string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, entry := range zr.File {
+		path, err := safeJoin(dest, entry.Name)
+		if err != nil {
+			return err
+		}
+
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, os.ModePerm); err != nil {
+				return err
+			}
+			continue
+		}
+
+		reader, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(path, reader, entry.Mode())
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyTree(source string, dest string) error {
+	return filepath.WalkDir(source, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if d.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return writeFile(target, file, info.Mode())
+	})
+}
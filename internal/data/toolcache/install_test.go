@@ -0,0 +1,190 @@
+package toolcache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTarGz(t *testing.T, path string, files map[string]string) string {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+
+	data, _ := os.ReadFile(path)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestInstallArchive(t *testing.T) {
+	var dir = t.TempDir()
+	var cache = Cache{Dir: filepath.Join(dir, "tools")}
+	var archive = filepath.Join(dir, "foo-1.2.tar.gz")
+	var sum = writeTarGz(t, archive, map[string]string{
+		"foo-1.2/bin/foo":   "#!/bin/sh\n",
+		"foo-1.2/README.md": "foo",
+	})
+
+	if _, err := cache.Install("foo", "1.2", archive, InstallOptions{Sha256: "00"}); err == nil {
+		t.Fatalf("got: nil, want: checksum mismatch error")
+	}
+
+	if err := os.WriteFile(archive+".sha256", []byte(sum+"  foo-1.2.tar.gz\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var path, err = cache.Install("foo", "1.2", dir, InstallOptions{})
+	var expected = filepath.Join(cache.Dir, "foo", "1.2", "foo-1.2", "bin", "foo")
+	if err != nil || path != expected {
+		t.Fatalf("got: %s, %v, want: %s, nil", path, err, expected)
+	}
+
+	installed, err := cache.List()
+	if err != nil || len(installed) != 1 || installed[0].Version != "1.2" {
+		t.Fatalf("got: %v, %v, want: foo@1.2 installed", installed, err)
+	}
+}
+
+func TestInstallRejectsEscapingEntries(t *testing.T) {
+	var dir = t.TempDir()
+	var cache = Cache{Dir: filepath.Join(dir, "tools")}
+	var archive = filepath.Join(dir, "evil.tar.gz")
+	var sum = writeTarGz(t, archive, map[string]string{"../../evil": "x"})
+
+	if _, err := cache.Install("evil", "1", archive, InstallOptions{Sha256: sum}); err == nil {
+		t.Fatalf("got: nil, want: error for entry outside install directory")
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "evil")); err == nil {
+		t.Fatalf("got: file written outside install directory, want: no file")
+	}
+}
+
+type tarEntry struct {
+	name    string
+	link    string
+	content string
+}
+
+// writeTarEntries writes an archive holding entries in order, which are
+// symlinks if they have a link.
+func writeTarEntries(t *testing.T, path string, entries []tarEntry) string {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0755, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if entry.link != "" {
+			header = &tar.Header{Name: entry.name, Linkname: entry.link, Mode: 0777, Typeflag: tar.TypeSymlink}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+
+	data, _ := os.ReadFile(path)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestInstallRejectsEscapingSymlinks(t *testing.T) {
+	var dir = t.TempDir()
+	var outside = filepath.Join(dir, "outside")
+	if err := os.Mkdir(outside, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name    string
+		entries []tarEntry
+	}{
+		{"absolute", []tarEntry{{name: "link", link: outside}, {name: "link/pwned", content: "x"}}},
+		{"relative", []tarEntry{{name: "link", link: "../../../outside"}, {name: "link/pwned", content: "x"}}},
+		{"through symlink", []tarEntry{
+			{name: "d/x", link: ".."},
+			{name: "y", link: "d/x/../../outside"},
+			{name: "y/pwned", content: "x"},
+		}},
+		{"into symlink", []tarEntry{{name: "d/x", link: "."}, {name: "d/x/pwned", content: "x"}}},
+	}
+
+	for _, tc := range tests {
+		var cache = Cache{Dir: filepath.Join(dir, "tools")}
+		var archive = filepath.Join(dir, "evil.tar")
+		var sum = writeTarEntries(t, archive, tc.entries)
+
+		if _, err := cache.Install("evil", "1", archive, InstallOptions{Sha256: sum, Binary: "d"}); err == nil {
+			t.Fatalf("%s got: nil, want: error for symlink outside install directory", tc.name)
+		}
+		if _, err := os.Stat(filepath.Join(outside, "pwned")); err == nil {
+			t.Fatalf("%s got: file written outside install directory, want: no file", tc.name)
+		}
+	}
+
+	// Symlinks within the install directory, including to other symlinks,
+	// are kept
+	var cache = Cache{Dir: filepath.Join(dir, "tools")}
+	var archive = filepath.Join(dir, "good.tar")
+	var sum = writeTarEntries(t, archive, []tarEntry{
+		{name: "lib/foo-1.2/bin/foo", content: "#!/bin/sh\n"},
+		{name: "lib/foo", link: "foo-1.2"},
+		{name: "bin/foo", link: "../lib/foo/bin/foo"},
+	})
+	if _, err := cache.Install("good", "1", archive, InstallOptions{Sha256: sum, Binary: "bin/foo"}); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+}
+
+func TestInstallDirectoryNeedsChecksum(t *testing.T) {
+	var dir = t.TempDir()
+	var cache = Cache{Dir: filepath.Join(dir, "tools")}
+	var source = filepath.Join(dir, "foo")
+	if err := os.MkdirAll(filepath.Join(source, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var content = []byte("#!/bin/sh\n")
+	if err := os.WriteFile(filepath.Join(source, "bin", "foo"), content, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.Install("foo", "1.2", source, InstallOptions{}); err == nil {
+		t.Fatalf("got: nil, want: error for missing checksum")
+	}
+
+	var sum = sha256.Sum256(content)
+	var sums = hex.EncodeToString(sum[:]) + "  foo\n"
+	if err := os.WriteFile(filepath.Join(source, "bin", "SHA256SUMS"), []byte(sums), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Install("foo", "1.2", source, InstallOptions{}); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+}
@@ -0,0 +1,278 @@
+package display
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// secretNamePattern matches the names of environment variables which are
+// likely to hold secrets.
+var secretNamePattern = regexp.MustCompile(`(?i)(TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|PRIVATE|(^|_)KEY$|API_?KEY)`)
+
+// minSecretLength is the length below which values aren't masked, since
+// masking short values like "1" would hide unrelated output.
+const minSecretLength = 4
+
+// GithubObserver writes output for GitHub Actions: each step in a
+// collapsible group, an error annotation pointing at the definition of each
+// failing step, and a Markdown summary of the run in $GITHUB_STEP_SUMMARY.
+// Values of environment variables which look like secrets are masked in
+// the log. GitHub doesn't nest groups, so flows are marked by plain lines.
+type GithubObserver struct {
+	mu       sync.Mutex
+	writer   io.Writer
+	project  *ilofile.Definition
+	recorder *exec.Recorder
+	// stopToken resumes workflow commands after the output of the running
+	// step, which mustn't be able to issue commands of its own.
+	stopToken string
+}
+
+func init() {
+	exec.RegisterObserver("github", func(opts exec.ObserverOptions) (exec.ExecutionObserver, error) {
+		return NewGithubObserver(opts.Project, opts.Output), nil
+	})
+}
+
+// InGithubActions reports whether ilo is running in a GitHub Actions job.
+func InGithubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+func NewGithubObserver(project *ilofile.Definition, writer io.Writer) *GithubObserver {
+	o := &GithubObserver{writer: writer, project: project, recorder: exec.NewRecorder()}
+	o.maskSecrets()
+	return o
+}
+
+// maskSecrets asks GitHub to hide the values of environment variables,
+// including those set by project tools, whose names look like secrets.
+func (o *GithubObserver) maskSecrets() {
+	values := make(map[string]bool)
+	addValue := func(name string, value string) {
+		if secretNamePattern.MatchString(name) {
+			for _, line := range strings.Split(value, "\n") {
+				if line = strings.TrimSpace(line); len(line) >= minSecretLength {
+					values[line] = true
+				}
+			}
+		}
+	}
+
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		addValue(name, value)
+	}
+	if o.project != nil {
+		for _, tool := range o.project.Toolbox {
+			for name, value := range tool.Env {
+				addValue(name, value)
+			}
+		}
+	}
+
+	sorted := make([]string, 0, len(values))
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+	sort.Strings(sorted)
+
+	for _, value := range sorted {
+		fmt.Fprintf(o.writer, "::add-mask::%s\n", escapeData(value))
+	}
+}
+
+// escapeData escapes the message of a workflow command.
+func escapeData(text string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(text)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(text string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(text)
+}
+
+func (o *GithubObserver) flowName(e exec.Event) string {
+	if o.project != nil && o.project.Name != "" {
+		return fmt.Sprintf("%s / %s", o.project.Name, e.Flow.Name)
+	}
+	return e.Flow.Name
+}
+
+// definitionPath returns the path of the project definition file relative
+// to the workspace, as annotations expect.
+func (o *GithubObserver) definitionPath() string {
+	if o.project == nil {
+		return ""
+	}
+
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	if workspace == "" {
+		workspace, _ = os.Getwd()
+	}
+	if rel, err := filepath.Rel(workspace, o.project.Path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return o.project.Path
+}
+
+func (o *GithubObserver) FlowEntered(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.FlowEntered(e)
+	fmt.Fprintf(o.writer, "Running flow %s\n", o.flowName(e))
+}
+
+func (o *GithubObserver) StepEntered(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.StepEntered(e)
+	fmt.Fprintf(o.writer, "::group::%s\n", escapeData(fmt.Sprintf("%s / %d: %s", o.flowName(e), e.StepIndex+1, e.Step)))
+
+	o.stopToken = newStopToken()
+	fmt.Fprintf(o.writer, "::stop-commands::%s\n", o.stopToken)
+}
+
+// newStopToken returns a token for stop-commands which step output can't
+// guess.
+func newStopToken() string {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(token)
+}
+
+// endStep resumes workflow commands and closes the group of the running
+// step.
+func (o *GithubObserver) endStep() {
+	if o.stopToken != "" {
+		fmt.Fprintf(o.writer, "::%s::\n", o.stopToken)
+		o.stopToken = ""
+	}
+	fmt.Fprintln(o.writer, "::endgroup::")
+}
+
+func (o *GithubObserver) StepOutput(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	fmt.Fprintln(o.writer, e.Text)
+}
+
+func (o *GithubObserver) StepPassed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.StepPassed(e)
+	o.endStep()
+}
+
+func (o *GithubObserver) StepFailed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.StepFailed(e)
+	o.endStep()
+
+	properties := []string{"title=" + escapeProperty(fmt.Sprintf("%s failed at step %d", o.flowName(e), e.StepIndex+1))}
+	if path := o.definitionPath(); path != "" {
+		properties = append(properties, "file="+escapeProperty(path))
+		if located, ok := e.Step.(ilofile.LocatedStep); ok && located.Line() > 0 {
+			properties = append(properties, fmt.Sprintf("line=%d", located.Line()))
+		}
+	}
+
+	fmt.Fprintf(o.writer, "::error %s::%s\n", strings.Join(properties, ","),
+		escapeData(fmt.Sprintf("%s: %s", e.Step, e.Err)))
+}
+
+func (o *GithubObserver) FlowPassed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.FlowPassed(e)
+	fmt.Fprintf(o.writer, "Flow %s passed in %s\n", o.flowName(e), e.Duration.Round(time.Millisecond))
+}
+
+func (o *GithubObserver) FlowFailed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.FlowFailed(e)
+	if e.Skipped {
+		fmt.Fprintf(o.writer, "Flow %s skipped, %s\n", o.flowName(e), e.Err)
+		return
+	}
+	fmt.Fprintf(o.writer, "Flow %s failed after %s\n", o.flowName(e), e.Duration.Round(time.Millisecond))
+}
+
+var summaryStatus = map[exec.Status]string{
+	exec.StatusPassed:  "✅ passed",
+	exec.StatusFailed:  "❌ failed",
+	exec.StatusSkipped: "⏭️ skipped",
+	exec.StatusRunning: "running",
+}
+
+// escapeMarkdown escapes text for a Markdown table cell.
+func escapeMarkdown(text string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ", "`", "\\`").Replace(text)
+}
+
+// Close appends a summary of the run to $GITHUB_STEP_SUMMARY, if it is set.
+func (o *GithubObserver) Close() error {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	flows := o.recorder.Flows()
+	if path == "" || len(flows) == 0 {
+		return nil
+	}
+
+	var sb strings.Builder
+	name := "ilo"
+	if o.project != nil && o.project.Name != "" {
+		name = "ilo: " + o.project.Name
+	}
+	fmt.Fprintf(&sb, "### %s\n\n", escapeMarkdown(name))
+	sb.WriteString("| Flow | Step | Status | Duration |\n")
+	sb.WriteString("| --- | --- | --- | --- |\n")
+
+	for _, flow := range flows {
+		fmt.Fprintf(&sb, "| **%s** | | %s | %s |\n", escapeMarkdown(flow.Name), summaryStatus[flow.Status],
+			flow.Duration().Round(time.Millisecond))
+
+		for _, step := range flow.Steps {
+			duration := ""
+			if step.Status == exec.StatusPassed || step.Status == exec.StatusFailed {
+				duration = step.Duration().Round(time.Millisecond).String()
+			}
+			fmt.Fprintf(&sb, "| | %d. `%s` | %s | %s |\n", step.Index+1, escapeMarkdown(step.Text),
+				summaryStatus[step.Status], duration)
+		}
+	}
+	sb.WriteString("\n")
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("write GitHub step summary: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(sb.String()); err != nil {
+		return fmt.Errorf("write GitHub step summary: %w", err)
+	}
+	return nil
+}
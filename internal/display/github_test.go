@@ -0,0 +1,86 @@
+package display
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+type locatedStep struct {
+	text string
+	line int
+}
+
+func (s locatedStep) StepType() ilofile.StepType { return ilofile.StepEchoMessage }
+func (s locatedStep) String() string             { return s.text }
+func (s locatedStep) Line() int                  { return s.line }
+
+func TestGithubObserver(t *testing.T) {
+	var workspace = t.TempDir()
+	var summaryPath = filepath.Join(t.TempDir(), "summary.md")
+	t.Setenv("GITHUB_WORKSPACE", workspace)
+	t.Setenv("GITHUB_STEP_SUMMARY", summaryPath)
+	t.Setenv("DEPLOY_TOKEN", "hunter2%secret")
+
+	var project = &ilofile.Definition{Name: "proj", Path: filepath.Join(workspace, "ci", "ilo.yml")}
+	var flow = ilofile.Flow{Name: "build", Project: project, Steps: []ilofile.Step{
+		locatedStep{"make", 3}, locatedStep{"make test", 4}, locatedStep{"make dist", 5},
+	}}
+
+	var buf bytes.Buffer
+	var observer = NewGithubObserver(project, &buf)
+
+	exec.RunFlow("run", flow, func(step ilofile.Step, params exec.ExecParams) error {
+		params.Output(exec.Stdout, "hello")
+		params.Output(exec.Stdout, "::error::injected")
+		if step.String() == "make test" {
+			return errors.New("exit status 2\nsee above")
+		}
+		return nil
+	}, nil, observer)
+
+	if err := observer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out = buf.String()
+	var group = regexp.MustCompile(`::group::proj / build / (\d): .*\n::stop-commands::(\w+)\nhello\n::error::injected\n::(\w+)::\n::endgroup::\n`)
+	var groups = group.FindAllStringSubmatch(out, -1)
+	if len(groups) != 2 || groups[0][2] != groups[0][3] || groups[1][2] != groups[1][3] || groups[0][2] == groups[1][2] {
+		t.Fatalf("got: %s, want: output of 2 steps between stop-commands with a new token for each", out)
+	}
+
+	for _, expected := range []string{
+		"::add-mask::hunter2%25secret\n",
+		"::error title=proj / build failed at step 2,file=ci/ilo.yml,line=4::make test: exit status 2%0Asee above\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("got: %s, want: output containing %q", out, expected)
+		}
+	}
+	if strings.Contains(out, "make dist") {
+		t.Fatalf("got: %s, want: no group for skipped step", out)
+	}
+
+	summary, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"### ilo: proj\n",
+		"| **build** | | ❌ failed |",
+		"| | 2. `make test` | ❌ failed |",
+		"| | 3. `make dist` | ⏭️ skipped |  |",
+	} {
+		if !strings.Contains(string(summary), expected) {
+			t.Fatalf("got: %s, want: summary containing %q", summary, expected)
+		}
+	}
+}
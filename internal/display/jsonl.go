@@ -0,0 +1,116 @@
+package display
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// JsonLinesEvent is a single line of output from a JsonLinesObserver.
+type JsonLinesEvent struct {
+	Time       time.Time `json:"time"`
+	Run        string    `json:"run"`
+	Event      string    `json:"event"`
+	Project    string    `json:"project,omitempty"`
+	Flow       string    `json:"flow,omitempty"`
+	Step       *int      `json:"step,omitempty"`
+	Text       string    `json:"text,omitempty"`
+	Stream     string    `json:"stream,omitempty"`
+	ExitCode   *int      `json:"exit_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs *int64    `json:"duration_ms,omitempty"`
+}
+
+// JsonLinesObserver writes one JSON object per line for every flow and step
+// event and every line of step output, for consumption by other programs.
+type JsonLinesObserver struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	project *ilofile.Definition
+}
+
+func init() {
+	exec.RegisterObserver("jsonl", func(opts exec.ObserverOptions) (exec.ExecutionObserver, error) {
+		return NewJsonLinesObserver(opts.Project, opts.Output), nil
+	})
+}
+
+func NewJsonLinesObserver(project *ilofile.Definition, writer io.Writer) *JsonLinesObserver {
+	return &JsonLinesObserver{
+		encoder: json.NewEncoder(writer),
+		project: project,
+	}
+}
+
+func (o *JsonLinesObserver) emit(e exec.Event, line JsonLinesEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	line.Time = e.Time
+	line.Run = e.RunID
+	if o.project != nil {
+		line.Project = o.project.Path
+	}
+	if e.Flow != nil {
+		line.Flow = e.Flow.Name
+	}
+	if e.StepIndex >= 0 {
+		stepIndex := e.StepIndex
+		line.Step = &stepIndex
+	}
+
+	// Output is best-effort, a broken pipe shouldn't stop the flow
+	_ = o.encoder.Encode(line)
+}
+
+func durationMs(duration time.Duration) *int64 {
+	ms := duration.Milliseconds()
+	return &ms
+}
+
+func (o *JsonLinesObserver) FlowEntered(e exec.Event) {
+	o.emit(e, JsonLinesEvent{Event: "flow_entered"})
+}
+
+func (o *JsonLinesObserver) StepEntered(e exec.Event) {
+	o.emit(e, JsonLinesEvent{Event: "step_entered", Text: e.Step.String()})
+}
+
+func (o *JsonLinesObserver) StepOutput(e exec.Event) {
+	o.emit(e, JsonLinesEvent{Event: "step_output", Stream: e.Stream.String(), Text: e.Text})
+}
+
+func (o *JsonLinesObserver) StepPassed(e exec.Event) {
+	exitCode := 0
+	o.emit(e, JsonLinesEvent{
+		Event:      "step_passed",
+		ExitCode:   &exitCode,
+		DurationMs: durationMs(e.Duration),
+	})
+}
+
+func (o *JsonLinesObserver) StepFailed(e exec.Event) {
+	exitCode := e.ExitCode
+	o.emit(e, JsonLinesEvent{
+		Event:      "step_failed",
+		ExitCode:   &exitCode,
+		Error:      e.Err.Error(),
+		DurationMs: durationMs(e.Duration),
+	})
+}
+
+func (o *JsonLinesObserver) FlowPassed(e exec.Event) {
+	o.emit(e, JsonLinesEvent{Event: "flow_passed", DurationMs: durationMs(e.Duration)})
+}
+
+func (o *JsonLinesObserver) FlowFailed(e exec.Event) {
+	if e.Skipped {
+		o.emit(e, JsonLinesEvent{Event: "flow_skipped", Error: e.Err.Error()})
+		return
+	}
+	o.emit(e, JsonLinesEvent{Event: "flow_failed", DurationMs: durationMs(e.Duration)})
+}
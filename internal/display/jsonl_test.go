@@ -0,0 +1,80 @@
+package display
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+func TestJsonLinesObserver(t *testing.T) {
+	var project = &ilofile.Definition{Name: "proj", Path: "/work/ilo.yml"}
+	var flow = ilofile.Flow{Name: "build", Project: project, Steps: []ilofile.Step{
+		locatedStep{"make", 3}, locatedStep{"make test", 4}, locatedStep{"make dist", 5},
+	}}
+
+	var buf bytes.Buffer
+	exec.RunFlow("run-1", flow, func(step ilofile.Step, params exec.ExecParams) error {
+		params.Output(exec.Stdout, step.String()+" out")
+		if step.String() == "make test" {
+			params.Output(exec.Stderr, "FAIL")
+			return errors.New("tests failed")
+		}
+		return nil
+	}, nil, NewJsonLinesObserver(project, &buf))
+
+	var events []JsonLinesEvent
+	var scanner = bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event JsonLinesEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("got: %v decoding %q, want: nil", err, scanner.Text())
+		}
+		if event.Run != "run-1" || event.Project != "/work/ilo.yml" || event.Flow != "build" || event.Time.IsZero() {
+			t.Fatalf("got: %+v, want: run, project, flow and time on every event", event)
+		}
+		events = append(events, event)
+	}
+
+	var names []string
+	for _, event := range events {
+		names = append(names, event.Event)
+	}
+	var expected = []string{
+		"flow_entered",
+		"step_entered", "step_output", "step_passed",
+		"step_entered", "step_output", "step_output", "step_failed",
+		"flow_failed",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("got: %v, want: %v", names, expected)
+	}
+
+	if events[0].Step != nil || events[len(events)-1].Step != nil {
+		t.Fatalf("got: %v and %v, want: no step on flow events", events[0].Step, events[len(events)-1].Step)
+	}
+
+	var failed = events[7]
+	if failed.Step == nil || *failed.Step != 1 || failed.ExitCode == nil || *failed.ExitCode != -1 ||
+		failed.Error != "tests failed" || failed.DurationMs == nil {
+		t.Fatalf("got: %+v, want: step 1 failed with exit code -1 and a duration", failed)
+	}
+
+	var passed = events[3]
+	if passed.Step == nil || *passed.Step != 0 || passed.ExitCode == nil || *passed.ExitCode != 0 || passed.Error != "" {
+		t.Fatalf("got: %+v, want: step 0 passed with exit code 0", passed)
+	}
+
+	var stderr = events[6]
+	if stderr.Stream != "stderr" || stderr.Text != "FAIL" || events[5].Stream != "stdout" || events[5].Text != "make test out" {
+		t.Fatalf("got: %+v and %+v, want: stdout then stderr output", events[5], stderr)
+	}
+	if events[1].Text != "make" {
+		t.Fatalf("got: %q, want: step text on step_entered", events[1].Text)
+	}
+}
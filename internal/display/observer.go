@@ -5,57 +5,80 @@ import (
 	"log"
 	"time"
 
+	"github.com/fourls/ilo/internal/exec"
 	"github.com/fourls/ilo/internal/ilofile"
 )
 
 type CliObserver struct {
-	logger    *log.Logger
-	project   *ilofile.Definition
-	flow      *ilofile.Flow
-	step      ilofile.Step
-	flowStart time.Time
+	logger   *log.Logger
+	project  *ilofile.Definition
+	recorder *exec.Recorder
+}
+
+func init() {
+	exec.RegisterObserver("text", func(opts exec.ObserverOptions) (exec.ExecutionObserver, error) {
+		observer := NewObserver(opts.Project, log.New(opts.Output, "", 0))
+		return &observer, nil
+	})
 }
 
 func NewObserver(project *ilofile.Definition, logger *log.Logger) CliObserver {
-	return CliObserver{project: project, logger: logger}
+	return CliObserver{project: project, logger: logger, recorder: exec.NewRecorder()}
 }
 
-func (o *CliObserver) FlowEntered(f *ilofile.Flow) {
-	o.flow = f
-	flowId := fmt.Sprintf("%s / %s", o.project.Name, o.flow.Name)
-	HorizontalRule{Header: flowId}.Print(o.logger)
+// Close prints a summary of every flow observed.
+func (o *CliObserver) Close() error {
+	if flows := o.recorder.Flows(); len(flows) > 0 {
+		Summary{Project: o.project.Name, Flows: flows}.Print(o.logger)
+	}
+	return nil
+}
 
-	o.flowStart = time.Now()
+func (o *CliObserver) FlowEntered(e exec.Event) {
+	o.recorder.FlowEntered(e)
+	flowId := fmt.Sprintf("%s / %s", o.project.Name, e.Flow.Name)
+	HorizontalRule{Header: flowId}.Print(o.logger)
 }
 
-func (o *CliObserver) StepEntered(s ilofile.Step) {
-	o.step = s
+func (o *CliObserver) StepEntered(e exec.Event) {
+	o.recorder.StepEntered(e)
 }
 
-func (o *CliObserver) StepOutput(text string) {
-	o.logger.Println(text)
+func (o *CliObserver) StepOutput(e exec.Event) {
+	o.logger.Println(e.Text)
 }
 
-func (o *CliObserver) StepPassed() {
-	o.step = nil
+func (o *CliObserver) StepPassed(e exec.Event) {
+	o.recorder.StepPassed(e)
 }
 
-func (o *CliObserver) StepFailed(err error) {
-	o.logger.Println(err.Error())
+func (o *CliObserver) StepFailed(e exec.Event) {
+	o.recorder.StepFailed(e)
+	o.logger.Println(e.Err.Error())
 }
 
-func (o *CliObserver) FlowPassed() {
-	o.flow = nil
+func (o *CliObserver) FlowPassed(e exec.Event) {
+	o.recorder.FlowPassed(e)
+	status := fmt.Sprintf("PASSED in %s", e.Duration.Round(time.Millisecond))
+	HorizontalRule{Footer: status, FooterStyle: StylePassed}.Print(o.logger)
+}
 
-	duration := time.Since(o.flowStart).Round(time.Millisecond)
-	status := fmt.Sprintf("PASSED in %s", duration)
-	HorizontalRule{Footer: status}.Print(o.logger)
+func (o *CliObserver) FlowFailed(e exec.Event) {
+	o.recorder.FlowFailed(e)
+	HorizontalRule{Footer: flowFailedStatus(e), FooterStyle: flowFailedStyle(e)}.Print(o.logger)
 }
 
-func (o *CliObserver) FlowFailed() {
-	o.flow = nil
+// flowFailedStatus describes a flow which failed, or which was skipped.
+func flowFailedStatus(e exec.Event) string {
+	if e.Skipped {
+		return fmt.Sprintf("SKIPPED, %s", e.Err)
+	}
+	return fmt.Sprintf("FAILED after %s", e.Duration.Round(time.Millisecond))
+}
 
-	duration := time.Since(o.flowStart).Round(time.Millisecond)
-	status := fmt.Sprintf("FAILED after %s", duration)
-	HorizontalRule{Footer: status}.Print(o.logger)
+func flowFailedStyle(e exec.Event) Style {
+	if e.Skipped {
+		return StyleDim
+	}
+	return StyleFailed
 }
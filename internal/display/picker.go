@@ -0,0 +1,337 @@
+package display
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// PickerItem is an entry which can be chosen in a picker.
+type PickerItem struct {
+	Name        string
+	Description string
+}
+
+// maxPickerRows is the most items a picker shows at once.
+const maxPickerRows = 10
+
+// Pick shows an interactive picker on a terminal, reading keys from in and
+// drawing on out. Typing filters the items by fuzzy matching, tab selects
+// several items, and enter chooses the selected items, or the highlighted
+// one if none are selected. It returns no names if the user cancels.
+func Pick(in *os.File, out *os.File, prompt string, items []PickerItem) ([]string, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("start picker: %w", err)
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	theme := ThemeFor(out)
+	rows := maxPickerRows
+	if _, height, err := term.GetSize(int(out.Fd())); err == nil && height > 0 {
+		rows = max(min(rows, height-3), 1)
+	}
+
+	p := newPicker(items)
+	drawn := 0
+	draw := func(lines []string) {
+		var sb strings.Builder
+		if drawn > 0 {
+			fmt.Fprintf(&sb, "\x1b[%dA\x1b[J", drawn)
+		}
+		for _, line := range lines {
+			sb.WriteString(line + "\r\n")
+		}
+		drawn = len(lines)
+		out.WriteString(sb.String())
+	}
+
+	out.WriteString("\x1b[?25l")
+	defer out.WriteString("\x1b[?25h")
+
+	var buf [64]byte
+	for {
+		draw(p.render(theme, prompt, rows))
+
+		n, err := in.Read(buf[:])
+		if err != nil {
+			draw(nil)
+			return nil, fmt.Errorf("read key: %w", err)
+		}
+
+		for _, k := range parseKeys(buf[:n]) {
+			if done := p.handle(k); done {
+				draw(nil)
+				return p.chosen(), nil
+			}
+		}
+	}
+}
+
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyUp
+	keyDown
+	keyTab
+	keyEnter
+	keyBackspace
+	keyClear
+	keyCancel
+	keyUnknown
+)
+
+type key struct {
+	kind keyKind
+	r    rune
+}
+
+// parseKeys reads the keys pressed from a chunk of terminal input.
+func parseKeys(input []byte) []key {
+	var keys []key
+	for len(input) > 0 {
+		switch {
+		case len(input) >= 3 && (input[0] == 0x1b) && (input[1] == '[' || input[1] == 'O'):
+			switch input[2] {
+			case 'A':
+				keys = append(keys, key{kind: keyUp})
+			case 'B':
+				keys = append(keys, key{kind: keyDown})
+			default:
+				keys = append(keys, key{kind: keyUnknown})
+			}
+			input = input[3:]
+			continue
+		case input[0] == 0x1b, input[0] == 0x03:
+			keys = append(keys, key{kind: keyCancel})
+		case input[0] == '\r', input[0] == '\n':
+			keys = append(keys, key{kind: keyEnter})
+		case input[0] == '\t':
+			keys = append(keys, key{kind: keyTab})
+		case input[0] == 0x7f, input[0] == 0x08:
+			keys = append(keys, key{kind: keyBackspace})
+		case input[0] == 0x10:
+			keys = append(keys, key{kind: keyUp})
+		case input[0] == 0x0e:
+			keys = append(keys, key{kind: keyDown})
+		case input[0] == 0x15:
+			keys = append(keys, key{kind: keyClear})
+		default:
+			r, size := utf8.DecodeRune(input)
+			if unicode.IsPrint(r) {
+				keys = append(keys, key{kind: keyRune, r: r})
+			} else {
+				keys = append(keys, key{kind: keyUnknown})
+			}
+			input = input[size:]
+			continue
+		}
+		input = input[1:]
+	}
+	return keys
+}
+
+// picker is the state of a picker, separate from the terminal.
+type picker struct {
+	items    []PickerItem
+	query    []rune
+	matches  []int
+	cursor   int
+	offset   int
+	selected []int
+	// cancelled is set when the user leaves without choosing anything.
+	cancelled bool
+}
+
+func newPicker(items []PickerItem) *picker {
+	p := &picker{items: items}
+	p.filter()
+	return p
+}
+
+// filter matches items against the query, best match first.
+func (p *picker) filter() {
+	type match struct{ index, score int }
+	var matches []match
+
+	for i, item := range p.items {
+		score, ok := fuzzyScore(string(p.query), item.Name)
+		if descScore, descOk := fuzzyScore(string(p.query), item.Description); !ok && descOk {
+			// Matches on the description rank below matches on the name
+			score, ok = descScore-100, true
+		}
+		if ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+
+	p.matches = make([]int, len(matches))
+	for i, m := range matches {
+		p.matches[i] = m.index
+	}
+	p.cursor, p.offset = 0, 0
+}
+
+// handle applies a key press, returning whether the picker is finished.
+func (p *picker) handle(k key) bool {
+	switch k.kind {
+	case keyRune:
+		p.query = append(p.query, k.r)
+		p.filter()
+	case keyBackspace:
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			p.filter()
+		}
+	case keyClear:
+		p.query = nil
+		p.filter()
+	case keyUp:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case keyDown:
+		if p.cursor+1 < len(p.matches) {
+			p.cursor++
+		}
+	case keyTab:
+		if len(p.matches) > 0 {
+			index := p.matches[p.cursor]
+			if i := slices.Index(p.selected, index); i >= 0 {
+				p.selected = slices.Delete(p.selected, i, i+1)
+			} else {
+				p.selected = append(p.selected, index)
+			}
+			if p.cursor+1 < len(p.matches) {
+				p.cursor++
+			}
+		}
+	case keyEnter:
+		return len(p.selected) > 0 || len(p.matches) > 0
+	case keyCancel:
+		p.cancelled = true
+		return true
+	}
+	return false
+}
+
+// chosen returns the names of the chosen items, in the order they were
+// selected.
+func (p *picker) chosen() []string {
+	if p.cancelled {
+		return nil
+	}
+
+	indices := p.selected
+	if len(indices) == 0 && len(p.matches) > 0 {
+		indices = []int{p.matches[p.cursor]}
+	}
+
+	names := make([]string, len(indices))
+	for i, index := range indices {
+		names[i] = p.items[index].Name
+	}
+	return names
+}
+
+// render draws the picker as lines, showing at most rows items.
+func (p *picker) render(theme Theme, prompt string, rows int) []string {
+	g := theme.Glyphs
+	width := theme.Width
+
+	query := string(p.query)
+	if query == "" {
+		query = theme.Paint(StyleDim, "type to filter, tab to select, enter to run")
+	}
+	lines := []string{Truncate(theme.Paint(StyleBold, prompt)+" "+query, width, g.Ellipsis)}
+
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	} else if p.cursor >= p.offset+rows {
+		p.offset = p.cursor - rows + 1
+	}
+
+	nameWidth := 0
+	for _, item := range p.items {
+		nameWidth = max(nameWidth, DisplayWidth(item.Name))
+	}
+
+	for i := p.offset; i < min(p.offset+rows, len(p.matches)); i++ {
+		item := p.items[p.matches[i]]
+
+		pointer := "  "
+		name := Pad(item.Name, nameWidth)
+		if i == p.cursor {
+			pointer = g.Pointer + " "
+			name = theme.Paint(StyleBold, name)
+		}
+
+		check := g.Unchecked
+		if slices.Contains(p.selected, p.matches[i]) {
+			check = theme.Paint(StylePassed, g.Checked)
+		}
+
+		line := pointer + check + " " + name
+		if item.Description != "" {
+			line += "  " + theme.Paint(StyleDim, item.Description)
+		}
+		lines = append(lines, Truncate(line, width, g.Ellipsis))
+	}
+
+	status := fmt.Sprintf("%d/%d", len(p.matches), len(p.items))
+	if len(p.selected) > 0 {
+		status += fmt.Sprintf(", %d selected", len(p.selected))
+	}
+	lines = append(lines, theme.Paint(StyleDim, status))
+	return lines
+}
+
+// fuzzyScore matches pattern against text as a case-insensitive
+// subsequence. Matches score higher when their characters are adjacent or
+// start words, and lower when spread out.
+func fuzzyScore(pattern string, text string) (int, bool) {
+	if pattern == "" {
+		return 0, true
+	}
+
+	patternRunes := []rune(strings.ToLower(pattern))
+	textRunes := []rune(text)
+
+	score, p, last := 0, 0, -1
+	for i, r := range textRunes {
+		if p == len(patternRunes) {
+			break
+		}
+		if unicode.ToLower(r) != patternRunes[p] {
+			continue
+		}
+
+		switch {
+		case last >= 0 && i == last+1:
+			score += 8
+		case i == 0 || !unicode.IsLetter(textRunes[i-1]) && !unicode.IsDigit(textRunes[i-1]):
+			score += 6
+		case last >= 0:
+			score -= min(i-last-1, 4)
+		}
+		if i == 0 {
+			score += 4
+		}
+
+		last = i
+		p++
+	}
+
+	if p < len(patternRunes) {
+		return 0, false
+	}
+	return score, true
+}
@@ -0,0 +1,52 @@
+package display
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPickerFiltersAndSelects(t *testing.T) {
+	var p = newPicker([]PickerItem{
+		{Name: "build", Description: "Compile the binaries"},
+		{Name: "bench-unit"},
+		{Name: "lint"},
+		{Name: "test-unit", Description: "Run the unit tests"},
+	})
+
+	var filters = []struct {
+		query    string
+		expected []int
+	}{
+		{"bu", []int{0, 1}},
+		{"\x15tu", []int{3}},
+		{"\x15compile", []int{0}},
+	}
+
+	for _, tc := range filters {
+		for _, k := range parseKeys([]byte(tc.query)) {
+			p.handle(k)
+		}
+		if !reflect.DeepEqual(p.matches, tc.expected) {
+			t.Fatalf("filter %q got: %v, want: %v", tc.query, p.matches, tc.expected)
+		}
+	}
+
+	// Select test-unit, then bench-unit, and run them in that order
+	for _, k := range parseKeys([]byte("\x15unit\x1b[B\t\x1b[A\t")) {
+		p.handle(k)
+	}
+	if done := p.handle(key{kind: keyEnter}); !done {
+		t.Fatalf("got: not done, want: done")
+	}
+	if got, want := p.chosen(), []string{"test-unit", "bench-unit"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestPickerCancel(t *testing.T) {
+	var p = newPicker([]PickerItem{{Name: "build"}})
+
+	if done := p.handle(parseKeys([]byte{0x03})[0]); !done || p.chosen() != nil {
+		t.Fatalf("got: %v, want: nothing chosen", p.chosen())
+	}
+}
@@ -1,12 +1,8 @@
 package display
 
 import (
-	"fmt"
 	"log"
-	"os"
 	"strings"
-
-	"golang.org/x/term"
 )
 
 type Printer interface {
@@ -16,83 +12,92 @@ type Printer interface {
 type HorizontalRule struct {
 	Header string
 	Footer string
+	// FooterStyle highlights the footer, e.g. to show whether a flow passed.
+	FooterStyle Style
 }
 
 type InfoBox [][]string
 
-func getTermWidth() int {
-	width, _, err := term.GetSize(int(os.Stdin.Fd()))
-	if err != nil {
-		width = 50
-	}
-	return width
-}
-
-func (hr HorizontalRule) Print(log *log.Logger) {
-	width := getTermWidth() - len(log.Prefix())
+// Render draws the rule as lines for the given theme.
+func (hr HorizontalRule) Render(theme Theme, width int) []string {
+	g := theme.Glyphs
+	var lines []string
 
-	headerStart := -1
 	headerEnd := -1
-	footerStart := -1
 	footerEnd := -1
 
-	headerEdge := func(i int) bool {
-		return headerStart == i || headerEnd == i
-	}
-
-	footerEdge := func(i int) bool {
-		return footerStart == i || footerEnd == i
-	}
-
 	if hr.Header != "" {
-		headerStart = 0
-		headerEnd = len(hr.Header) + 3
-		log.Printf("┌%s┐\n", strings.Repeat("─", len(hr.Header)+2))
-		log.Printf("│ %s │\n", hr.Header)
+		headerWidth := DisplayWidth(hr.Header)
+		headerEnd = headerWidth + 3
+		lines = append(lines,
+			g.TopLeft+strings.Repeat(g.Horizontal, headerWidth+2)+g.TopRight,
+			g.Vertical+" "+theme.Paint(StyleBold, hr.Header)+" "+g.Vertical)
 	}
 
 	if hr.Footer != "" {
-		footerStart = 0
-		footerEnd = len(hr.Footer) + 3
+		footerEnd = DisplayWidth(hr.Footer) + 3
 	}
 
 	var sb strings.Builder
-
 	for i := range width {
+		headerEdge := hr.Header != "" && (i == 0 || i == headerEnd)
+		footerEdge := hr.Footer != "" && (i == 0 || i == footerEnd)
+
 		switch {
-		case !headerEdge(i) && !footerEdge(i):
-			sb.WriteRune('─')
-		case headerEdge(i) && !footerEdge(i):
-			sb.WriteRune('┴')
-		case !headerEdge(i) && footerEdge(i):
-			sb.WriteRune('┬')
-		case headerEdge(i) && footerEdge(i):
-			sb.WriteRune('┼')
+		case !headerEdge && !footerEdge:
+			sb.WriteString(g.Horizontal)
+		case headerEdge && !footerEdge:
+			sb.WriteString(g.TeeUp)
+		case !headerEdge && footerEdge:
+			sb.WriteString(g.TeeDown)
+		default:
+			sb.WriteString(g.Cross)
 		}
 	}
-
-	log.Print(sb.String())
+	lines = append(lines, sb.String())
 
 	if hr.Footer != "" {
-		log.Printf("│ %s │\n", hr.Footer)
-		log.Printf("└%s┘\n", strings.Repeat("─", len(hr.Footer)+2))
+		footerWidth := DisplayWidth(hr.Footer)
+		lines = append(lines,
+			g.Vertical+" "+theme.Paint(hr.FooterStyle, hr.Footer)+" "+g.Vertical,
+			g.BottomLeft+strings.Repeat(g.Horizontal, footerWidth+2)+g.BottomRight)
 	}
+	return lines
 }
 
-func (b InfoBox) Print(log *log.Logger) {
-	width := getTermWidth() - len(log.Prefix())
-	log.Printf(`╔%s╗`, strings.Repeat("═", width-2))
+func (hr HorizontalRule) Print(log *log.Logger) {
+	theme := ThemeFor(log.Writer())
+	for _, line := range hr.Render(theme, theme.Width-len(log.Prefix())) {
+		log.Println(line)
+	}
+}
 
-	separator := fmt.Sprintf("╟%s╢\n", strings.Repeat("─", width-2))
+// Render draws the box as lines for the given theme, cutting lines which
+// don't fit.
+func (b InfoBox) Render(theme Theme, width int) []string {
+	g := theme.Glyphs
+	inner := max(width-4, 1)
 
-	for i, lines := range b {
-		for _, line := range lines {
-			log.Printf("║ %-*s ║\n", width-4, line)
+	lines := []string{g.DoubleTopLeft + strings.Repeat(g.DoubleHorizontal, inner+2) + g.DoubleTopRight}
+	separator := g.DoubleTeeRight + strings.Repeat(g.Horizontal, inner+2) + g.DoubleTeeLeft
+
+	for i, section := range b {
+		for _, line := range section {
+			line = Pad(Truncate(line, inner, g.Ellipsis), inner)
+			lines = append(lines, g.DoubleVertical+" "+line+" "+g.DoubleVertical)
 		}
 		if i+1 < len(b) {
-			log.Print(separator)
+			lines = append(lines, separator)
 		}
 	}
 
-	log.Printf(`╚%s╝`, strings.Repeat("═", width-2))
+	lines = append(lines, g.DoubleBottomLeft+strings.Repeat(g.DoubleHorizontal, inner+2)+g.DoubleBottomRight)
+	return lines
+}
+
+func (b InfoBox) Print(log *log.Logger) {
+	theme := ThemeFor(log.Writer())
+	for _, line := range b.Render(theme, theme.Width-len(log.Prefix())) {
+		log.Println(line)
+	}
 }
@@ -0,0 +1,280 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// progressTail is the number of output lines shown under a running step.
+const progressTail = 5
+
+// progressInterval is how often the spinner and elapsed time are redrawn.
+const progressInterval = 100 * time.Millisecond
+
+var (
+	unicodeSpinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	asciiSpinner   = []string{"|", "/", "-", "\\"}
+)
+
+// ProgressObserver shows a compact, live view of a run on a terminal: the
+// running step with a spinner and its elapsed time, above the last few
+// lines of its output. Steps that pass collapse to a single line, and the
+// full output of a step that fails is printed once it fails.
+type ProgressObserver struct {
+	mu       sync.Mutex
+	writer   io.Writer
+	logger   *log.Logger
+	theme    Theme
+	project  *ilofile.Definition
+	recorder *exec.Recorder
+
+	// running holds the steps being run, in the order they were entered.
+	running []*runningStep
+	// drawn is the number of lines in the live view currently on screen.
+	drawn int
+	frame int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+type runningStep struct {
+	event  exec.Event
+	output []string
+}
+
+func init() {
+	exec.RegisterObserver("progress", func(opts exec.ObserverOptions) (exec.ExecutionObserver, error) {
+		return NewProgressObserver(opts.Project, opts.Output), nil
+	})
+
+	exec.RegisterObserver("auto", func(opts exec.ObserverOptions) (exec.ExecutionObserver, error) {
+		return autoObserver(opts, ThemeFor(opts.Output)), nil
+	})
+}
+
+// autoObserver uses GitHub Actions output in GitHub Actions, shows progress
+// on a terminal, and plain text anywhere else.
+func autoObserver(opts exec.ObserverOptions, theme Theme) exec.ExecutionObserver {
+	if InGithubActions() {
+		return NewGithubObserver(opts.Project, opts.Output)
+	}
+	if theme.TTY && os.Getenv("CI") == "" {
+		return newProgressObserver(opts.Project, opts.Output, theme)
+	}
+	observer := NewObserver(opts.Project, log.New(opts.Output, "", 0))
+	return &observer
+}
+
+func NewProgressObserver(project *ilofile.Definition, writer io.Writer) *ProgressObserver {
+	return newProgressObserver(project, writer, ThemeFor(writer))
+}
+
+func newProgressObserver(project *ilofile.Definition, writer io.Writer, theme Theme) *ProgressObserver {
+	return &ProgressObserver{
+		writer:   writer,
+		logger:   log.New(writer, "", 0),
+		theme:    theme,
+		project:  project,
+		recorder: exec.NewRecorder(),
+	}
+}
+
+// start begins redrawing the live view, if it isn't already being redrawn.
+func (o *ProgressObserver) start() {
+	if o.stop != nil {
+		return
+	}
+
+	o.stop = make(chan struct{})
+	o.done = make(chan struct{})
+	go func(stop chan struct{}, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				o.mu.Lock()
+				o.frame += 1
+				o.redraw()
+				o.mu.Unlock()
+			}
+		}
+	}(o.stop, o.done)
+}
+
+// clear removes the live view from the screen, so that permanent output
+// can be written in its place.
+func (o *ProgressObserver) clear() {
+	if o.drawn > 0 {
+		fmt.Fprintf(o.writer, "\x1b[%dA\x1b[J", o.drawn)
+		o.drawn = 0
+	}
+}
+
+func (o *ProgressObserver) redraw() {
+	o.clear()
+
+	spinner := unicodeSpinner
+	if o.theme.ASCII {
+		spinner = asciiSpinner
+	}
+
+	// Lines must not wrap, or clearing would leave part of them behind
+	width := o.theme.Width - 1
+	var lines []string
+	for _, step := range o.running {
+		prefix := fmt.Sprintf("%s %d: ", o.theme.Paint(StyleWarning, spinner[o.frame%len(spinner)]),
+			step.event.StepIndex+1)
+		suffix := o.theme.Paint(StyleDim, fmt.Sprintf(" (%s)", time.Since(step.event.Time).Round(time.Second)))
+		text := Truncate(step.event.Step.String(), width-DisplayWidth(prefix+suffix), o.theme.Glyphs.Ellipsis)
+		lines = append(lines, prefix+text+suffix)
+
+		for _, line := range step.output[max(len(step.output)-progressTail, 0):] {
+			lines = append(lines, o.theme.Paint(StyleDim, "  "+line))
+		}
+	}
+
+	for _, line := range lines {
+		fmt.Fprintln(o.writer, Truncate(line, width, o.theme.Glyphs.Ellipsis))
+	}
+	o.drawn = len(lines)
+}
+
+// print writes permanent output above the live view.
+func (o *ProgressObserver) print(printer func()) {
+	o.clear()
+	printer()
+	o.redraw()
+}
+
+func (o *ProgressObserver) find(e exec.Event) (int, *runningStep) {
+	for i, step := range o.running {
+		if step.event.RunID == e.RunID && step.event.Flow == e.Flow {
+			return i, step
+		}
+	}
+	return -1, nil
+}
+
+func (o *ProgressObserver) FlowEntered(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.FlowEntered(e)
+	o.print(func() {
+		HorizontalRule{Header: fmt.Sprintf("%s / %s", o.project.Name, e.Flow.Name)}.Print(o.logger)
+	})
+}
+
+func (o *ProgressObserver) StepEntered(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.StepEntered(e)
+	o.running = append(o.running, &runningStep{event: e})
+	o.start()
+	o.redraw()
+}
+
+func (o *ProgressObserver) StepOutput(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, step := o.find(e); step != nil {
+		step.output = append(step.output, e.Text)
+	}
+}
+
+func (o *ProgressObserver) finishStep(e exec.Event) *runningStep {
+	i, step := o.find(e)
+	if step != nil {
+		o.running = append(o.running[:i], o.running[i+1:]...)
+	}
+	return step
+}
+
+func (o *ProgressObserver) StepPassed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.StepPassed(e)
+	o.finishStep(e)
+	o.print(func() {
+		o.logger.Printf("%s %d: %s %s", o.theme.StatusMark(exec.StatusPassed), e.StepIndex+1, e.Step.String(),
+			o.theme.Paint(StyleDim, fmt.Sprintf("(%s)", e.Duration.Round(time.Millisecond))))
+	})
+}
+
+func (o *ProgressObserver) StepFailed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.StepFailed(e)
+	step := o.finishStep(e)
+	o.print(func() {
+		o.logger.Printf("%s %d: %s %s", o.theme.StatusMark(exec.StatusFailed), e.StepIndex+1, e.Step.String(),
+			o.theme.Paint(StyleDim, fmt.Sprintf("(%s)", e.Duration.Round(time.Millisecond))))
+		if step != nil {
+			for _, line := range step.output {
+				o.logger.Println("  " + line)
+			}
+		}
+		o.logger.Println(o.theme.Paint(StyleFailed, "  "+e.Err.Error()))
+	})
+}
+
+func (o *ProgressObserver) FlowPassed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.FlowPassed(e)
+	o.print(func() {
+		status := fmt.Sprintf("PASSED in %s", e.Duration.Round(time.Millisecond))
+		HorizontalRule{Footer: status, FooterStyle: StylePassed}.Print(o.logger)
+	})
+}
+
+func (o *ProgressObserver) FlowFailed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.recorder.FlowFailed(e)
+	o.print(func() {
+		HorizontalRule{Footer: flowFailedStatus(e), FooterStyle: flowFailedStyle(e)}.Print(o.logger)
+	})
+}
+
+// Close stops redrawing the live view and prints a summary of every flow
+// observed.
+func (o *ProgressObserver) Close() error {
+	o.mu.Lock()
+	stop, done := o.stop, o.done
+	o.stop = nil
+	o.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.clear()
+	if flows := o.recorder.Flows(); len(flows) > 0 {
+		Summary{Project: o.project.Name, Flows: flows}.Print(o.logger)
+	}
+	return nil
+}
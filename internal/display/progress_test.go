@@ -0,0 +1,121 @@
+package display
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+var clearPattern = regexp.MustCompile(`\x1b\[(\d+)A\x1b\[J`)
+
+// screen returns the lines left on a terminal by output which clears the
+// live view by moving the cursor up and erasing.
+func screen(out string) []string {
+	var lines []string
+	for {
+		loc := clearPattern.FindStringSubmatchIndex(out)
+		text := out
+		if loc != nil {
+			text = out[:loc[0]]
+		}
+		lines = append(lines, strings.SplitAfter(text, "\n")...)
+		if lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if loc == nil {
+			return lines
+		}
+
+		n, _ := strconv.Atoi(out[loc[2]:loc[3]])
+		lines = lines[:len(lines)-n]
+		out = out[loc[1]:]
+	}
+}
+
+func TestProgressObserver(t *testing.T) {
+	var project = &ilofile.Definition{Name: "proj"}
+	var flow = ilofile.Flow{Name: "build", Project: project, Steps: []ilofile.Step{
+		locatedStep{"make", 3}, locatedStep{"make test", 4}, locatedStep{"make dist", 5},
+	}}
+
+	var buf bytes.Buffer
+	var theme = Theme{TTY: true, Width: 80, Glyphs: unicodeGlyphs}
+	var observer = newProgressObserver(project, &buf, theme)
+
+	var live []string
+	exec.RunFlow("run", flow, func(step ilofile.Step, params exec.ExecParams) error {
+		for i := 1; i <= 7; i++ {
+			params.Output(exec.Stdout, fmt.Sprintf("%s line %d", step, i))
+		}
+		if step.String() == "make" {
+			observer.mu.Lock()
+			observer.redraw()
+			live = screen(buf.String())
+			observer.mu.Unlock()
+			return nil
+		}
+		return errors.New("exit status 2")
+	}, nil, observer)
+
+	var tail = strings.Join(live[len(live)-6:], "")
+	if !regexp.MustCompile(`^\S 1: make \(\d+s\)\n  make line 3\n`).MatchString(tail) ||
+		!strings.HasSuffix(tail, "  make line 7\n") {
+		t.Fatalf("got: %q, want: running step with the last 5 lines of output", tail)
+	}
+
+	if err := observer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out = strings.Join(screen(buf.String()), "")
+	if !regexp.MustCompile(`\n✓ 1: make \(\d+m?s\)\n✗ 2: make test`).MatchString(out) {
+		t.Fatalf("got: %s, want: passed step collapsed to one line", out)
+	}
+	if strings.Contains(out, "make line") {
+		t.Fatalf("got: %s, want: no output from the passed step", out)
+	}
+	for i := 1; i <= 7; i++ {
+		if !strings.Contains(out, fmt.Sprintf("\n  make test line %d\n", i)) {
+			t.Fatalf("got: %s, want: all output from the failed step", out)
+		}
+	}
+	if !strings.Contains(out, "\n  exit status 2\n") || strings.Count(out, "make dist") != 1 {
+		t.Fatalf("got: %s, want: error after the output and the skipped step only in the summary", out)
+	}
+}
+
+func TestAutoObserver(t *testing.T) {
+	var opts = exec.ObserverOptions{Project: &ilofile.Definition{Name: "proj"}, Output: &bytes.Buffer{}}
+	var terminal = Theme{TTY: true, Width: 80, Glyphs: unicodeGlyphs}
+
+	var tests = []struct {
+		theme    Theme
+		ci       string
+		github   string
+		expected string
+	}{
+		{terminal, "", "", "*display.ProgressObserver"},
+		{terminal, "true", "", "*display.CliObserver"},
+		{Theme{Width: 80}, "", "", "*display.CliObserver"},
+		{terminal, "true", "true", "*display.GithubObserver"},
+	}
+
+	for _, tc := range tests {
+		t.Setenv("CI", tc.ci)
+		t.Setenv("GITHUB_ACTIONS", tc.github)
+		var observer = autoObserver(opts, tc.theme)
+		if got := fmt.Sprintf("%T", observer); got != tc.expected {
+			t.Fatalf("got: %s, want: %s for TTY %v, CI %q", got, tc.expected, tc.theme.TTY, tc.ci)
+		}
+		if closer, ok := observer.(interface{ Close() error }); ok {
+			closer.Close()
+		}
+	}
+}
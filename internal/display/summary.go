@@ -0,0 +1,155 @@
+package display
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+)
+
+// slowestSteps is the number of steps highlighted in a summary.
+const slowestSteps = 3
+
+// stepColumns is the width taken up by everything in a step line except
+// its text, including the box border.
+const stepColumns = 36
+
+// Summary lists every flow and step of a run with its outcome, highlighting
+// the slowest steps.
+type Summary struct {
+	Project string
+	Flows   []exec.FlowRecord
+}
+
+// StatusMark returns the symbol for a status, highlighted to match.
+func (t Theme) StatusMark(status exec.Status) string {
+	switch status {
+	case exec.StatusPassed:
+		return t.Paint(StylePassed, t.Glyphs.Passed)
+	case exec.StatusFailed:
+		return t.Paint(StyleFailed, t.Glyphs.Failed)
+	case exec.StatusSkipped:
+		return t.Paint(StyleDim, t.Glyphs.Skipped)
+	default:
+		return t.Glyphs.Running
+	}
+}
+
+// StatusStyle returns the style used to highlight a status.
+func StatusStyle(status exec.Status) Style {
+	switch status {
+	case exec.StatusPassed:
+		return StylePassed
+	case exec.StatusFailed:
+		return StyleFailed
+	case exec.StatusSkipped:
+		return StyleDim
+	default:
+		return StylePlain
+	}
+}
+
+type stepRef struct {
+	flow, step int
+}
+
+// slowest returns the slowest steps that ran, leaving out the fastest so
+// that at most half the steps are highlighted.
+func (s Summary) slowest() map[stepRef]bool {
+	var ran []stepRef
+	for i, flow := range s.Flows {
+		for j, step := range flow.Steps {
+			if step.Status == exec.StatusPassed || step.Status == exec.StatusFailed {
+				ran = append(ran, stepRef{i, j})
+			}
+		}
+	}
+
+	sort.SliceStable(ran, func(a, b int) bool {
+		return s.Flows[ran[a].flow].Steps[ran[a].step].Duration() >
+			s.Flows[ran[b].flow].Steps[ran[b].step].Duration()
+	})
+
+	count := min(slowestSteps, len(ran)/2)
+	slowest := make(map[stepRef]bool)
+	for _, ref := range ran[:max(count, 0)] {
+		slowest[ref] = true
+	}
+	return slowest
+}
+
+// InfoBox builds a box with a section for each flow and a total, to be
+// drawn with theme in the given width.
+func (s Summary) InfoBox(theme Theme, width int) InfoBox {
+	slowest := s.slowest()
+	box := InfoBox{}
+
+	textWidth := 0
+	for _, flow := range s.Flows {
+		for _, step := range flow.Steps {
+			textWidth = max(textWidth, DisplayWidth(fmt.Sprintf("%d: %s", step.Index+1, step.Text)))
+		}
+	}
+	textWidth = max(min(textWidth, width-stepColumns), 10)
+
+	passed, failed, skipped := 0, 0, 0
+	var total time.Duration
+
+	for i, flow := range s.Flows {
+		switch flow.Status {
+		case exec.StatusPassed:
+			passed += 1
+		case exec.StatusSkipped:
+			skipped += 1
+		default:
+			failed += 1
+		}
+		total += flow.Duration()
+
+		status := Pad(theme.Paint(StatusStyle(flow.Status), strings.ToUpper(string(flow.Status))), 7)
+		header := fmt.Sprintf("%s %s / %s in %s", status, s.Project, flow.Name, flow.Duration().Round(time.Millisecond))
+		if flow.Status == exec.StatusSkipped {
+			header = fmt.Sprintf("%s %s / %s, %s", status, s.Project, flow.Name, flow.Error)
+		}
+		lines := []string{header}
+
+		for j, step := range flow.Steps {
+			text := Truncate(fmt.Sprintf("%d: %s", step.Index+1, step.Text), textWidth, theme.Glyphs.Ellipsis)
+			line := fmt.Sprintf("  %s %s", theme.StatusMark(step.Status), Pad(text, textWidth))
+
+			if step.Status == exec.StatusPassed || step.Status == exec.StatusFailed {
+				line += fmt.Sprintf(" %9s  exit %-3d", step.Duration().Round(time.Millisecond), step.ExitCode)
+			} else {
+				line += theme.Paint(StyleDim, fmt.Sprintf(" %9s", step.Status))
+			}
+			if slowest[stepRef{i, j}] {
+				line += "  " + theme.Paint(StyleWarning, theme.Glyphs.Highlighted+" slow")
+			}
+			lines = append(lines, line)
+		}
+		box = append(box, lines)
+	}
+
+	flows := "flows"
+	if len(s.Flows) == 1 {
+		flows = "flow"
+	}
+	counts := fmt.Sprintf("%d passed, %d failed", passed, failed)
+	if skipped > 0 {
+		counts += fmt.Sprintf(", %d skipped", skipped)
+	}
+	box = append(box, []string{fmt.Sprintf("%d %s: %s in %s",
+		len(s.Flows), flows, counts, total.Round(time.Millisecond))})
+	return box
+}
+
+func (s Summary) Print(log *log.Logger) {
+	theme := ThemeFor(log.Writer())
+	width := theme.Width - len(log.Prefix())
+	for _, line := range s.InfoBox(theme, width).Render(theme, width) {
+		log.Println(line)
+	}
+}
@@ -0,0 +1,152 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ColorMode chooses when output is coloured.
+type ColorMode string
+
+const (
+	// ColorAuto colours output written to a terminal, unless NO_COLOR is
+	// set or the terminal is dumb.
+	ColorAuto   ColorMode = "auto"
+	ColorAlways ColorMode = "always"
+	ColorNever  ColorMode = "never"
+)
+
+// ParseColorMode parses the value of a --color flag.
+func ParseColorMode(text string) (ColorMode, error) {
+	switch mode := ColorMode(text); mode {
+	case ColorAuto, ColorAlways, ColorNever:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid color mode '%s', expected auto, always or never", text)
+	}
+}
+
+// Options are the user's display preferences.
+type Options struct {
+	Color ColorMode
+	// ASCII draws borders and symbols with ASCII characters only.
+	ASCII bool
+}
+
+// DefaultOptions are used by ThemeFor, and are set from the command line.
+var DefaultOptions = Options{Color: ColorAuto}
+
+// defaultWidth is the width used when output isn't going to a terminal.
+const defaultWidth = 80
+
+// Theme describes how to draw output for a particular writer.
+type Theme struct {
+	Color  bool
+	ASCII  bool
+	TTY    bool
+	Width  int
+	Glyphs Glyphs
+}
+
+// ThemeFor detects the theme to use for output written to w, using the
+// default options.
+func ThemeFor(w io.Writer) Theme {
+	return DefaultOptions.Theme(w)
+}
+
+// Theme detects the theme to use for output written to w.
+func (o Options) Theme(w io.Writer) Theme {
+	theme := Theme{ASCII: o.ASCII, Width: defaultWidth, Glyphs: unicodeGlyphs}
+	if o.ASCII {
+		theme.Glyphs = asciiGlyphs
+	}
+
+	if file, ok := w.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		theme.TTY = true
+		if width, _, err := term.GetSize(int(file.Fd())); err == nil && width > 0 {
+			theme.Width = width
+		}
+	}
+
+	switch o.Color {
+	case ColorAlways:
+		theme.Color = true
+	case ColorNever:
+		theme.Color = false
+	default:
+		theme.Color = theme.TTY && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+	}
+	return theme
+}
+
+// Style is a way of highlighting text.
+type Style int
+
+const (
+	StylePlain Style = iota
+	StyleBold
+	StyleDim
+	StylePassed
+	StyleFailed
+	StyleWarning
+)
+
+var styleCodes = map[Style]string{
+	StyleBold:    "\x1b[1m",
+	StyleDim:     "\x1b[2m",
+	StylePassed:  "\x1b[32m",
+	StyleFailed:  "\x1b[31m",
+	StyleWarning: "\x1b[33m",
+}
+
+// Paint highlights text in a style, if the theme uses colour.
+func (t Theme) Paint(style Style, text string) string {
+	code, exists := styleCodes[style]
+	if !t.Color || !exists || text == "" {
+		return text
+	}
+	return code + text + "\x1b[0m"
+}
+
+// Glyphs are the characters used to draw borders and symbols.
+type Glyphs struct {
+	Horizontal, Vertical                          string
+	TopLeft, TopRight, BottomLeft, BottomRight    string
+	TeeUp, TeeDown, Cross                         string
+	DoubleHorizontal, DoubleVertical              string
+	DoubleTopLeft, DoubleTopRight                 string
+	DoubleBottomLeft, DoubleBottomRight           string
+	DoubleTeeRight, DoubleTeeLeft                 string
+	Passed, Failed, Running, Skipped, Highlighted string
+	Pointer, Checked, Unchecked                   string
+	Ellipsis                                      string
+}
+
+var unicodeGlyphs = Glyphs{
+	Horizontal: "─", Vertical: "│",
+	TopLeft: "┌", TopRight: "┐", BottomLeft: "└", BottomRight: "┘",
+	TeeUp: "┴", TeeDown: "┬", Cross: "┼",
+	DoubleHorizontal: "═", DoubleVertical: "║",
+	DoubleTopLeft: "╔", DoubleTopRight: "╗",
+	DoubleBottomLeft: "╚", DoubleBottomRight: "╝",
+	DoubleTeeRight: "╟", DoubleTeeLeft: "╢",
+	Passed: "✓", Failed: "✗", Running: "…", Skipped: "-", Highlighted: "«",
+	Pointer: "›", Checked: "◉", Unchecked: "○",
+	Ellipsis: "…",
+}
+
+var asciiGlyphs = Glyphs{
+	Horizontal: "-", Vertical: "|",
+	TopLeft: "+", TopRight: "+", BottomLeft: "+", BottomRight: "+",
+	TeeUp: "+", TeeDown: "+", Cross: "+",
+	DoubleHorizontal: "=", DoubleVertical: "|",
+	DoubleTopLeft: "+", DoubleTopRight: "+",
+	DoubleBottomLeft: "+", DoubleBottomRight: "+",
+	DoubleTeeRight: "+", DoubleTeeLeft: "+",
+	Passed: "+", Failed: "x", Running: "~", Skipped: "-", Highlighted: "<",
+	Pointer: ">", Checked: "[x]", Unchecked: "[ ]",
+	Ellipsis: "~",
+}
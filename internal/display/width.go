@@ -0,0 +1,94 @@
+package display
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// RuneWidth returns the number of terminal columns a rune takes up: two for
+// wide characters such as CJK and most emoji, and none for combining marks
+// and other invisible characters.
+func RuneWidth(r rune) int {
+	switch {
+	case r == 0 || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) || unicode.Is(unicode.Cf, r):
+		return 0
+	case unicode.IsControl(r):
+		return 0
+	}
+
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// DisplayWidth returns the number of terminal columns text takes up,
+// ignoring ANSI escape sequences.
+func DisplayWidth(text string) int {
+	total := 0
+	for len(text) > 0 {
+		if n := escapeLength(text); n > 0 {
+			text = text[n:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text)
+		total += RuneWidth(r)
+		text = text[size:]
+	}
+	return total
+}
+
+// escapeLength returns the length of the ANSI escape sequence at the start
+// of text, or 0 if there isn't one.
+func escapeLength(text string) int {
+	if !strings.HasPrefix(text, "\x1b[") {
+		return 0
+	}
+	for i := 2; i < len(text); i++ {
+		if text[i] >= 0x40 && text[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(text)
+}
+
+// Pad adds spaces to the end of text until it takes up columns.
+func Pad(text string, columns int) string {
+	return text + strings.Repeat(" ", max(columns-DisplayWidth(text), 0))
+}
+
+// Truncate cuts text down to at most columns, marking the cut with
+// ellipsis. Escape sequences are kept so that styles are still reset.
+func Truncate(text string, columns int, ellipsis string) string {
+	if DisplayWidth(text) <= columns {
+		return text
+	}
+
+	limit := columns - DisplayWidth(ellipsis)
+	var sb strings.Builder
+	used := 0
+	for len(text) > 0 {
+		if n := escapeLength(text); n > 0 {
+			sb.WriteString(text[:n])
+			text = text[n:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(text)
+		if w := RuneWidth(r); used+w <= limit {
+			sb.WriteRune(r)
+			used += w
+		} else if limit >= 0 {
+			sb.WriteString(ellipsis)
+			limit = -1
+		}
+		text = text[size:]
+	}
+	return sb.String()
+}
@@ -0,0 +1,37 @@
+package display
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	var tests = []struct {
+		text     string
+		expected int
+	}{
+		{"build", 5},
+		{"✓ passed", 8},
+		{"构建", 4},
+		{"🚀 ship", 7},
+		{"é", 1},
+		{"\x1b[32mok\x1b[0m", 2},
+	}
+
+	for _, tc := range tests {
+		if got := DisplayWidth(tc.text); got != tc.expected {
+			t.Fatalf("DisplayWidth(%q) got: %d, want: %d", tc.text, got, tc.expected)
+		}
+	}
+}
+
+func TestInfoBoxAlignment(t *testing.T) {
+	var theme = Options{Color: ColorAlways}.Theme(nil)
+	var box = InfoBox{{"构建 🚀", theme.Paint(StylePassed, "passed"), strings.Repeat("long ", 20)}}
+
+	for _, line := range box.Render(theme, 30) {
+		if got := DisplayWidth(line); got != 30 {
+			t.Fatalf("got: %d columns in %q, want: 30", got, line)
+		}
+	}
+}
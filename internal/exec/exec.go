@@ -1,27 +1,39 @@
 package exec
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/fourls/ilo/internal/data/toolbox"
 	"github.com/fourls/ilo/internal/ilofile"
 )
 
-func printLines(text string, println func(string)) {
+func printLines(text string, output OutputFunc) {
 	for _, line := range strings.Split(strings.TrimRight(text, "\r\n"), "\n") {
-		println(strings.TrimRight(line, "\r"))
+		output(Stdout, strings.TrimRight(line, "\r"))
 	}
 }
 
+// OutputFunc reports a line of output written by a step.
+type OutputFunc func(stream OutputStream, text string)
+
 type ExecParams struct {
+	// Context stops the step's program when it is done, if set.
+	Context   context.Context
 	Env       []string
 	Directory string
-	Observer  ExecutionObserver
+	Output    OutputFunc
 	Toolbox   toolbox.Toolbox
+	// ToolVersions pins the version used for tools referenced without one.
+	ToolVersions map[string]string
 }
 
 func doRunStep(step ilofile.RunFlowStep, params ExecParams) error {
@@ -31,34 +43,62 @@ func doRunStep(step ilofile.RunFlowStep, params ExecParams) error {
 	}
 
 	firstArg := args[0]
+	env := params.Env
+	dir := params.Directory
+
 	if strings.HasPrefix(firstArg, "$") {
-		var path, exists = params.Toolbox[firstArg[1:]]
-		if exists {
-			firstArg = path
-		} else {
-			return errors.New("execute run step: no tool found for substitution " + firstArg)
+		name, version := toolbox.ParseRef(firstArg[1:])
+		if version == "" {
+			version = params.ToolVersions[name]
 		}
-	}
 
-	var cmd = exec.Command(firstArg, args[1:]...)
+		var tool, err = params.Toolbox.Find(name, version)
+		if err != nil {
+			return fmt.Errorf("execute run step: substitute %s: %w", firstArg, err)
+		}
 
-	cmd.Env = params.Env
-	cmd.Dir = params.Directory
+		firstArg = tool.Path
+		args = append(slices.Clone(tool.Args), args[1:]...)
+		env = append(slices.Clone(env), tool.Environ()...)
 
-	// todo read stderr
-	var out, err = cmd.Output()
+		if tool.Dir != "" {
+			dir = tool.Dir
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(params.Directory, dir)
+			}
+		}
+	} else {
+		args = args[1:]
+	}
 
-	if len(out) > 0 {
-		printLines(string(out), params.Observer.StepOutput)
+	var cmd *exec.Cmd
+	if params.Context != nil {
+		cmd = exec.CommandContext(params.Context, firstArg, args...)
+	} else {
+		cmd = exec.Command(firstArg, args...)
 	}
 
+	cmd.Env = env
+	cmd.Dir = dir
+
+	var mu sync.Mutex
+	var stdout = &lineWriter{mu: &mu, stream: Stdout, output: params.Output}
+	var stderr = &lineWriter{mu: &mu, stream: Stderr, output: params.Output}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	var err = cmd.Run()
+
+	stdout.Flush()
+	stderr.Flush()
+
 	return err
 }
 
 func RunStep(step ilofile.Step, params ExecParams) error {
 	switch step.StepType() {
 	case ilofile.StepEchoMessage:
-		printLines(step.(ilofile.EchoFlowStep).Message(), params.Observer.StepOutput)
+		printLines(step.(ilofile.EchoFlowStep).Message(), params.Output)
 		return nil
 	case ilofile.StepRunProgram:
 		return doRunStep(step.(ilofile.RunFlowStep), params)
@@ -78,33 +118,70 @@ func (e FlowExecutionError) Error() string {
 	return fmt.Sprintf("execute flow '%s': %s", e.FlowName, e.Message)
 }
 
-type ExecutionObserver interface {
-	FlowEntered(f *ilofile.Flow)
-	FlowPassed()
-	FlowFailed()
+// RunFlow executes all steps in the specified flow using stepExecutor,
+// and reports whether all steps were executed successfully.
+// If stepExecutor is omitted, steps will not be executed.
+// If provided, the observer will be called alongside various milestones
+// with events tagged with runID, see ExecutionObserver for more information.
+func RunFlow(
+	runID string,
+	flow ilofile.Flow,
+	stepExecutor StepExecutorFunc,
+	toolbox toolbox.Toolbox,
+	observer ExecutionObserver,
+) bool {
+	return RunFlowContext(context.Background(), runID, flow, stepExecutor, toolbox, observer)
+}
 
-	StepEntered(s ilofile.Step)
-	StepOutput(text string)
-	StepPassed()
-	StepFailed(err error)
+// RunPlan runs flows in order, as planned by ilofile.Definition.Plan, and
+// returns whether each one passed, by name. A flow which needs a flow that
+// didn't pass is skipped rather than run, and reported to the observer as
+// a skipped flow.
+func RunPlan(
+	runID string,
+	flows []ilofile.Flow,
+	stepExecutor StepExecutorFunc,
+	toolbox toolbox.Toolbox,
+	observer ExecutionObserver,
+) map[string]bool {
+	return RunPlanContext(context.Background(), runID, flows, stepExecutor, toolbox, observer)
 }
 
-type noOpObserver struct{}
+// RunPlanContext is RunPlan, but stops once ctx is done, see
+// RunFlowContext. Flows after one which is stopped are skipped if they
+// need it, and otherwise stop straight away.
+func RunPlanContext(
+	ctx context.Context,
+	runID string,
+	flows []ilofile.Flow,
+	stepExecutor StepExecutorFunc,
+	toolbox toolbox.Toolbox,
+	observer ExecutionObserver,
+) map[string]bool {
+	if observer == nil {
+		observer = noOpObserver{}
+	}
 
-func (o noOpObserver) FlowEntered(f *ilofile.Flow) {}
-func (o noOpObserver) FlowPassed()                 {}
-func (o noOpObserver) FlowFailed()                 {}
-func (o noOpObserver) StepEntered(s ilofile.Step)  {}
-func (o noOpObserver) StepOutput(text string)      {}
-func (o noOpObserver) StepPassed()                 {}
-func (o noOpObserver) StepFailed(err error)        {}
+	passed := make(map[string]bool)
+	for i := range flows {
+		if need := flows[i].FailedNeed(passed); need != "" {
+			event := Event{RunID: runID, Time: time.Now(), Flow: &flows[i], StepIndex: -1, Skipped: true,
+				Err: fmt.Errorf("needs '%s', which did not pass", need)}
+			observer.FlowEntered(event)
+			observer.FlowFailed(event)
+			passed[flows[i].Name] = false
+			continue
+		}
+		passed[flows[i].Name] = RunFlowContext(ctx, runID, flows[i], stepExecutor, toolbox, observer)
+	}
+	return passed
+}
 
-// RunFlow executes all steps in the specified flow using stepExecutor,
-// and reports whether all steps were executed successfully.
-// If stepExecutor is omitted, steps will not be executed.
-// If provided, the observer will be called alongside various milestones,
-// see ExecutionObserver for more information.
-func RunFlow(
+// RunFlowContext is RunFlow, but stops once ctx is done: the running step
+// fails with the context's error, and later steps don't run.
+func RunFlowContext(
+	ctx context.Context,
+	runID string,
 	flow ilofile.Flow,
 	stepExecutor StepExecutorFunc,
 	toolbox toolbox.Toolbox,
@@ -117,35 +194,65 @@ func RunFlow(
 		observer = noOpObserver{}
 	}
 
-	observer.FlowEntered(&flow)
+	flowEvent := Event{RunID: runID, Flow: &flow, StepIndex: -1}
+	flowStart := time.Now()
+	flowEvent.Time = flowStart
+	observer.FlowEntered(flowEvent)
 
 	baseParams := ExecParams{
+		Context:   ctx,
 		Env:       os.Environ(),
 		Directory: flow.Dir,
-		Observer:  observer,
 		Toolbox:   toolbox,
 	}
+	if flow.Project != nil {
+		baseParams.ToolVersions = flow.Project.Tools
+	}
 
 	success := true
 
 	for i := range flow.Steps {
-		observer.StepEntered(flow.Steps[i])
+		stepEvent := Event{RunID: runID, Flow: &flow, StepIndex: i, Step: flow.Steps[i]}
+		stepStart := time.Now()
+		stepEvent.Time = stepStart
+		observer.StepEntered(stepEvent)
+
+		params := baseParams
+		params.Output = func(stream OutputStream, text string) {
+			event := stepEvent
+			event.Time = time.Now()
+			event.Stream = stream
+			event.Text = text
+			observer.StepOutput(event)
+		}
 
-		err := stepExecutor(flow.Steps[i], baseParams)
+		err := ctx.Err()
+		if err == nil {
+			err = stepExecutor(flow.Steps[i], params)
+		}
+		if ctxErr := ctx.Err(); err != nil && ctxErr != nil {
+			err = fmt.Errorf("step cancelled: %w", ctxErr)
+		}
 
+		stepEvent.Time = time.Now()
+		stepEvent.Duration = stepEvent.Time.Sub(stepStart)
 		if err != nil {
-			observer.StepFailed(err)
+			stepEvent.Err = err
+			stepEvent.ExitCode = ExitCode(err)
+			observer.StepFailed(stepEvent)
 			success = false
 			break
 		} else {
-			observer.StepPassed()
+			observer.StepPassed(stepEvent)
 		}
 	}
 
+	flowEvent.Time = time.Now()
+	flowEvent.Duration = flowEvent.Time.Sub(flowStart)
 	if success {
-		observer.FlowPassed()
+		observer.FlowPassed(flowEvent)
 	} else {
-		observer.FlowFailed()
+		observer.FlowFailed(flowEvent)
 	}
 
 	return success
@@ -0,0 +1,60 @@
+package exec
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+func TestRunPlan(t *testing.T) {
+	var def = ilofile.Definition{Flows: map[string]ilofile.Flow{
+		"build":   {Name: "build", Steps: []ilofile.Step{programStep{"make"}}},
+		"test":    {Name: "test", Needs: []string{"build"}, Steps: []ilofile.Step{programStep{"make test"}}},
+		"release": {Name: "release", Needs: []string{"test"}, Steps: []ilofile.Step{programStep{"make release"}}},
+		"lint":    {Name: "lint", Steps: []ilofile.Step{programStep{"make lint"}}},
+		"docs":    {Name: "docs", Needs: []string{"lint"}, Steps: []ilofile.Step{programStep{"make docs"}}},
+	}}
+
+	flows, err := def.Plan("release", "docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	var recorder = NewRecorder()
+	var passed = RunPlan("run", flows, func(step ilofile.Step, params ExecParams) error {
+		ran = append(ran, step.String())
+		if step.String() == "make test" {
+			return errors.New("tests failed")
+		}
+		return nil
+	}, nil, recorder)
+
+	if expected := []string{"make", "make test", "make lint", "make docs"}; !reflect.DeepEqual(ran, expected) {
+		t.Fatalf("got: %v, want: %v", ran, expected)
+	}
+
+	var expected = map[string]bool{"build": true, "test": false, "release": false, "lint": true, "docs": true}
+	if !reflect.DeepEqual(passed, expected) {
+		t.Fatalf("got: %v, want: %v", passed, expected)
+	}
+
+	var statuses = make(map[string]Status)
+	for _, flow := range recorder.Flows() {
+		statuses[flow.Name] = flow.Status
+	}
+	var expectedStatuses = map[string]Status{
+		"build": StatusPassed, "test": StatusFailed, "release": StatusSkipped, "lint": StatusPassed, "docs": StatusPassed,
+	}
+	if !reflect.DeepEqual(statuses, expectedStatuses) {
+		t.Fatalf("got: %v, want: %v", statuses, expectedStatuses)
+	}
+
+	var release = recorder.Flows()[2]
+	if release.Name != "release" || release.Error != "needs 'test', which did not pass" ||
+		len(release.Steps) != 1 || release.Steps[0].Status != StatusSkipped {
+		t.Fatalf("got: %+v, want: release skipped with the reason and its step skipped", release)
+	}
+}
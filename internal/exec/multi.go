@@ -0,0 +1,195 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"sync"
+)
+
+// MultiObserver passes every event on to each of its observers in turn.
+// An observer that panics is reported through PanicHandler and receives no
+// further events, without affecting the other observers.
+type MultiObserver struct {
+	// PanicHandler is called when an observer panics. If nil, the panic is
+	// reported on stderr.
+	PanicHandler func(observer ExecutionObserver, value any)
+
+	mu        sync.Mutex
+	observers []ExecutionObserver
+	disabled  []bool
+}
+
+func NewMultiObserver(observers ...ExecutionObserver) *MultiObserver {
+	return &MultiObserver{
+		observers: observers,
+		disabled:  make([]bool, len(observers)),
+	}
+}
+
+// Add adds an observer, which receives every event from then on.
+func (m *MultiObserver) Add(observer ExecutionObserver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.observers = append(m.observers, observer)
+	m.disabled = append(m.disabled, false)
+}
+
+func (m *MultiObserver) each(event func(ExecutionObserver)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, observer := range m.observers {
+		if m.disabled[i] {
+			continue
+		}
+		if value := callIsolated(observer, event); value != nil {
+			m.disabled[i] = true
+			m.reportPanic(observer, value)
+		}
+	}
+}
+
+func (m *MultiObserver) reportPanic(observer ExecutionObserver, value any) {
+	if m.PanicHandler != nil {
+		m.PanicHandler(observer, value)
+	} else {
+		fmt.Fprintf(os.Stderr, "observer %T panicked and was disabled: %v\n", observer, value)
+	}
+}
+
+// callIsolated calls event on an observer, returning the value of any panic.
+func callIsolated(observer ExecutionObserver, event func(ExecutionObserver)) (value any) {
+	defer func() {
+		if r := recover(); r != nil {
+			value = fmt.Sprintf("%v\n%s", r, debug.Stack())
+		}
+	}()
+
+	event(observer)
+	return nil
+}
+
+// Close closes every observer that implements io.Closer, flushing any
+// queued events and writing any reports.
+func (m *MultiObserver) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for _, observer := range m.observers {
+		if closer, ok := observer.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MultiObserver) FlowEntered(e Event) {
+	m.each(func(o ExecutionObserver) { o.FlowEntered(e) })
+}
+
+func (m *MultiObserver) FlowPassed(e Event) {
+	m.each(func(o ExecutionObserver) { o.FlowPassed(e) })
+}
+
+func (m *MultiObserver) FlowFailed(e Event) {
+	m.each(func(o ExecutionObserver) { o.FlowFailed(e) })
+}
+
+func (m *MultiObserver) StepEntered(e Event) {
+	m.each(func(o ExecutionObserver) { o.StepEntered(e) })
+}
+
+func (m *MultiObserver) StepOutput(e Event) {
+	m.each(func(o ExecutionObserver) { o.StepOutput(e) })
+}
+
+func (m *MultiObserver) StepPassed(e Event) {
+	m.each(func(o ExecutionObserver) { o.StepPassed(e) })
+}
+
+func (m *MultiObserver) StepFailed(e Event) {
+	m.each(func(o ExecutionObserver) { o.StepFailed(e) })
+}
+
+// AsyncObserver queues events and passes them on to a slow observer from a
+// separate goroutine, so that it doesn't hold up the flow. Events are only
+// dropped if the observer panics; a full queue blocks until there is room.
+type AsyncObserver struct {
+	inner      ExecutionObserver
+	queue      chan func(ExecutionObserver)
+	done       chan struct{}
+	closed     sync.Once
+	panicValue any
+}
+
+// NewAsyncObserver starts passing events to inner through a queue holding
+// up to size events.
+func NewAsyncObserver(inner ExecutionObserver, size int) *AsyncObserver {
+	o := &AsyncObserver{
+		inner: inner,
+		queue: make(chan func(ExecutionObserver), size),
+		done:  make(chan struct{}),
+	}
+	go o.worker()
+	return o
+}
+
+func (o *AsyncObserver) worker() {
+	defer close(o.done)
+
+	for event := range o.queue {
+		if o.panicValue != nil {
+			continue
+		}
+		o.panicValue = callIsolated(o.inner, event)
+	}
+}
+
+// Close waits for every queued event to be handled, then closes the inner
+// observer if it implements io.Closer. A panic in the inner observer is
+// returned as an error.
+func (o *AsyncObserver) Close() error {
+	o.closed.Do(func() { close(o.queue) })
+	<-o.done
+
+	if o.panicValue != nil {
+		return fmt.Errorf("observer %T panicked: %v", o.inner, o.panicValue)
+	}
+	if closer, ok := o.inner.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (o *AsyncObserver) FlowEntered(e Event) {
+	o.queue <- func(inner ExecutionObserver) { inner.FlowEntered(e) }
+}
+
+func (o *AsyncObserver) FlowPassed(e Event) {
+	o.queue <- func(inner ExecutionObserver) { inner.FlowPassed(e) }
+}
+
+func (o *AsyncObserver) FlowFailed(e Event) {
+	o.queue <- func(inner ExecutionObserver) { inner.FlowFailed(e) }
+}
+
+func (o *AsyncObserver) StepEntered(e Event) {
+	o.queue <- func(inner ExecutionObserver) { inner.StepEntered(e) }
+}
+
+func (o *AsyncObserver) StepOutput(e Event) {
+	o.queue <- func(inner ExecutionObserver) { inner.StepOutput(e) }
+}
+
+func (o *AsyncObserver) StepPassed(e Event) {
+	o.queue <- func(inner ExecutionObserver) { inner.StepPassed(e) }
+}
+
+func (o *AsyncObserver) StepFailed(e Event) {
+	o.queue <- func(inner ExecutionObserver) { inner.StepFailed(e) }
+}
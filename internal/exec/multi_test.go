@@ -0,0 +1,68 @@
+package exec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+type recordingObserver struct {
+	noOpObserver
+	lines []string
+}
+
+func (o *recordingObserver) StepOutput(e Event) {
+	o.lines = append(o.lines, e.Text)
+}
+
+type panickingObserver struct {
+	noOpObserver
+}
+
+func (o panickingObserver) FlowEntered(e Event) {
+	panic("broken observer")
+}
+
+type legacyRecordingObserver struct {
+	lines []string
+}
+
+func (o *legacyRecordingObserver) FlowEntered(f *ilofile.Flow) {}
+func (o *legacyRecordingObserver) FlowPassed()                 {}
+func (o *legacyRecordingObserver) FlowFailed()                 {}
+func (o *legacyRecordingObserver) StepEntered(s ilofile.Step)  {}
+func (o *legacyRecordingObserver) StepPassed()                 {}
+func (o *legacyRecordingObserver) StepFailed(err error)        {}
+
+func (o *legacyRecordingObserver) StepOutput(stream OutputStream, text string) {
+	o.lines = append(o.lines, text)
+}
+
+func TestMultiObserverIsolatesPanics(t *testing.T) {
+	var recorder = &recordingObserver{}
+	var legacy = &legacyRecordingObserver{}
+	var panicked []any
+
+	var multi = NewMultiObserver(panickingObserver{}, recorder)
+	multi.Add(NewAsyncObserver(AdaptLegacy(legacy), 1))
+	multi.PanicHandler = func(observer ExecutionObserver, value any) {
+		panicked = append(panicked, value)
+	}
+
+	var flow = ilofile.Flow{Name: "build"}
+	multi.FlowEntered(Event{Flow: &flow, StepIndex: -1})
+	multi.StepOutput(Event{Flow: &flow, Text: "one"})
+	multi.StepOutput(Event{Flow: &flow, Text: "two"})
+	if err := multi.Close(); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var expected = []string{"one", "two"}
+	if !reflect.DeepEqual(recorder.lines, expected) || !reflect.DeepEqual(legacy.lines, expected) {
+		t.Fatalf("got: %v and %v, want: %v", recorder.lines, legacy.lines, expected)
+	}
+	if len(panicked) != 1 {
+		t.Fatalf("got: %d panics, want: 1", len(panicked))
+	}
+}
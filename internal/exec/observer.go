@@ -0,0 +1,90 @@
+package exec
+
+import (
+	"time"
+
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// Event describes something that happened while running a flow. Every
+// event carries enough context to be handled on its own, so one observer
+// can follow several flows running at once.
+type Event struct {
+	RunID string
+	Time  time.Time
+	Flow  *ilofile.Flow
+
+	// StepIndex is the index of Step in Flow.Steps, or -1 for flow events.
+	StepIndex int
+	Step      ilofile.Step
+
+	// Stream and Text are set for step output.
+	Stream OutputStream
+	Text   string
+
+	// Duration is set once a flow or step has finished.
+	Duration time.Duration
+	// Err and ExitCode are set when a step fails, see ExitCode.
+	Err      error
+	ExitCode int
+
+	// Skipped is set on the flow events of a flow which wasn't run because
+	// a flow it needs didn't pass. It is entered and then fails straight
+	// away, with Err saying why.
+	Skipped bool
+}
+
+// ExecutionObserver is notified as flows and steps start and finish, and
+// for every line of step output.
+type ExecutionObserver interface {
+	FlowEntered(e Event)
+	FlowPassed(e Event)
+	FlowFailed(e Event)
+
+	StepEntered(e Event)
+	StepOutput(e Event)
+	StepPassed(e Event)
+	StepFailed(e Event)
+}
+
+type noOpObserver struct{}
+
+func (o noOpObserver) FlowEntered(e Event) {}
+func (o noOpObserver) FlowPassed(e Event)  {}
+func (o noOpObserver) FlowFailed(e Event)  {}
+func (o noOpObserver) StepEntered(e Event) {}
+func (o noOpObserver) StepOutput(e Event)  {}
+func (o noOpObserver) StepPassed(e Event)  {}
+func (o noOpObserver) StepFailed(e Event)  {}
+
+// LegacyObserver is the observer interface from before events carried their
+// context. Such observers track the current flow and step themselves, so
+// they can only follow one flow at a time.
+type LegacyObserver interface {
+	FlowEntered(f *ilofile.Flow)
+	FlowPassed()
+	FlowFailed()
+
+	StepEntered(s ilofile.Step)
+	StepOutput(stream OutputStream, text string)
+	StepPassed()
+	StepFailed(err error)
+}
+
+// AdaptLegacy wraps a legacy observer so it can be used where an
+// ExecutionObserver is expected.
+func AdaptLegacy(observer LegacyObserver) ExecutionObserver {
+	return legacyAdapter{observer}
+}
+
+type legacyAdapter struct {
+	observer LegacyObserver
+}
+
+func (a legacyAdapter) FlowEntered(e Event) { a.observer.FlowEntered(e.Flow) }
+func (a legacyAdapter) FlowPassed(e Event)  { a.observer.FlowPassed() }
+func (a legacyAdapter) FlowFailed(e Event)  { a.observer.FlowFailed() }
+func (a legacyAdapter) StepEntered(e Event) { a.observer.StepEntered(e.Step) }
+func (a legacyAdapter) StepOutput(e Event)  { a.observer.StepOutput(e.Stream, e.Text) }
+func (a legacyAdapter) StepPassed(e Event)  { a.observer.StepPassed() }
+func (a legacyAdapter) StepFailed(e Event)  { a.observer.StepFailed(e.Err) }
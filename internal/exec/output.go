@@ -0,0 +1,90 @@
+package exec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutputStream identifies the stream a line of step output was written to.
+type OutputStream int
+
+const (
+	Stdout OutputStream = iota
+	Stderr
+)
+
+func (s OutputStream) String() string {
+	switch s {
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	default:
+		return "unknown"
+	}
+}
+
+// lineWriter splits everything written to it into lines and reports each
+// line to output. Writers sharing a mutex never report concurrently.
+type lineWriter struct {
+	mu     *sync.Mutex
+	stream OutputStream
+	output OutputFunc
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.output(w.stream, strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush reports any output not terminated by a newline.
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.output(w.stream, strings.TrimRight(string(w.buf), "\r"))
+		w.buf = nil
+	}
+}
+
+// ExitCode returns the exit code of the program that caused a step error,
+// 0 if there was no error, or -1 if the error didn't come from a program
+// exiting.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// NewRunID returns a unique identifier for a run, which sorts by the time
+// the run started.
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+}
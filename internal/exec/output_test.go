@@ -0,0 +1,74 @@
+package exec
+
+import (
+	"os/exec"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+type outputLine struct {
+	stream OutputStream
+	text   string
+}
+
+type programStep []string
+
+func (s programStep) StepType() ilofile.StepType { return ilofile.StepRunProgram }
+func (s programStep) String() string             { return s[0] }
+func (s programStep) Args() []string             { return s }
+
+func TestLineWriter(t *testing.T) {
+	var lines []outputLine
+	var writer = &lineWriter{mu: &sync.Mutex{}, stream: Stderr, output: func(stream OutputStream, text string) {
+		lines = append(lines, outputLine{stream, text})
+	}}
+
+	for _, chunk := range []string{"one\ntw", "o", "\r\n", "\nthr", "ee"} {
+		if n, err := writer.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Fatalf("got: %d, %v, want: %d, nil", n, err, len(chunk))
+		}
+	}
+
+	var expected = []outputLine{{Stderr, "one"}, {Stderr, "two"}, {Stderr, ""}}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("got: %v, want: %v", lines, expected)
+	}
+
+	writer.Flush()
+	writer.Flush()
+	expected = append(expected, outputLine{Stderr, "three"})
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("got: %v, want: %v", lines, expected)
+	}
+}
+
+func TestRunStepCapturesStreams(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	var lines []outputLine
+	var err = RunStep(programStep{"sh", "-c", "echo out; echo err >&2; printf partial; exit 3"}, ExecParams{
+		Output: func(stream OutputStream, text string) {
+			lines = append(lines, outputLine{stream, text})
+		},
+	})
+
+	if code := ExitCode(err); code != 3 {
+		t.Fatalf("got: exit code %d (%v), want: 3", code, err)
+	}
+
+	// The two streams are read separately, so only the order within each
+	// stream is known
+	var streams = map[OutputStream][]string{}
+	for _, line := range lines {
+		streams[line.stream] = append(streams[line.stream], line.text)
+	}
+	var expected = map[OutputStream][]string{Stdout: {"out", "partial"}, Stderr: {"err"}}
+	if !reflect.DeepEqual(streams, expected) {
+		t.Fatalf("got: %v, want: %v", streams, expected)
+	}
+}
@@ -0,0 +1,184 @@
+package exec
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// Status is the outcome of a flow or step.
+type Status string
+
+const (
+	StatusRunning Status = "running"
+	StatusPassed  Status = "passed"
+	StatusFailed  Status = "failed"
+	// StatusSkipped marks steps that never ran because an earlier step failed,
+	// and flows that never ran because a flow they need didn't pass.
+	StatusSkipped Status = "skipped"
+)
+
+// StepRecord is the recorded outcome of a single step.
+type StepRecord struct {
+	Index    int
+	Text     string
+	Status   Status
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+	Error    string
+}
+
+func (s StepRecord) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// FlowRecord is the recorded outcome of a flow and each of its steps.
+type FlowRecord struct {
+	RunID  string
+	Name   string
+	Status Status
+	Start  time.Time
+	End    time.Time
+	Steps  []StepRecord
+	// Error is why a skipped flow didn't run.
+	Error string
+}
+
+func (f FlowRecord) Duration() time.Duration {
+	return f.End.Sub(f.Start)
+}
+
+// Recorder is an observer which records when each flow and step it
+// observes started and finished, and how.
+type Recorder struct {
+	// FlowFinished, if set, is called with the record of each flow as it
+	// passes or fails.
+	FlowFinished func(FlowRecord)
+
+	mu    sync.Mutex
+	flows []FlowRecord
+	// running maps flows that have been entered but not finished to the
+	// index of their record.
+	running map[recordKey]int
+}
+
+type recordKey struct {
+	runID string
+	flow  *ilofile.Flow
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{running: make(map[recordKey]int)}
+}
+
+// Flows returns a copy of every flow recorded so far, in the order they
+// were entered.
+func (r *Recorder) Flows() []FlowRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	flows := slices.Clone(r.flows)
+	for i := range flows {
+		flows[i].Steps = slices.Clone(flows[i].Steps)
+	}
+	return flows
+}
+
+func (r *Recorder) flow(e Event) *FlowRecord {
+	return &r.flows[r.running[recordKey{e.RunID, e.Flow}]]
+}
+
+func (r *Recorder) step(e Event) *StepRecord {
+	return &r.flow(e).Steps[e.StepIndex]
+}
+
+func (r *Recorder) FlowEntered(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.running[recordKey{e.RunID, e.Flow}] = len(r.flows)
+	r.flows = append(r.flows, FlowRecord{
+		RunID:  e.RunID,
+		Name:   e.Flow.Name,
+		Status: StatusRunning,
+		Start:  e.Time,
+	})
+}
+
+func (r *Recorder) StepEntered(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	flow := r.flow(e)
+	flow.Steps = append(flow.Steps, StepRecord{
+		Index:  e.StepIndex,
+		Text:   e.Step.String(),
+		Status: StatusRunning,
+		Start:  e.Time,
+	})
+}
+
+func (r *Recorder) StepOutput(e Event) {}
+
+func (r *Recorder) StepPassed(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	step := r.step(e)
+	step.Status = StatusPassed
+	step.End = e.Time
+}
+
+func (r *Recorder) StepFailed(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	step := r.step(e)
+	step.Status = StatusFailed
+	step.End = e.Time
+	step.ExitCode = e.ExitCode
+	step.Error = e.Err.Error()
+}
+
+func (r *Recorder) FlowPassed(e Event) {
+	r.finishFlow(e, StatusPassed)
+}
+
+func (r *Recorder) FlowFailed(e Event) {
+	if e.Skipped {
+		r.finishFlow(e, StatusSkipped)
+	} else {
+		r.finishFlow(e, StatusFailed)
+	}
+}
+
+func (r *Recorder) finishFlow(e Event, status Status) {
+	r.mu.Lock()
+
+	flow := r.flow(e)
+	flow.Status = status
+	flow.End = e.Time
+	if e.Skipped && e.Err != nil {
+		flow.Error = e.Err.Error()
+	}
+
+	for i := len(flow.Steps); i < len(e.Flow.Steps); i++ {
+		flow.Steps = append(flow.Steps, StepRecord{
+			Index:  i,
+			Text:   e.Flow.Steps[i].String(),
+			Status: StatusSkipped,
+		})
+	}
+	delete(r.running, recordKey{e.RunID, e.Flow})
+
+	finished := *flow
+	finished.Steps = slices.Clone(flow.Steps)
+	r.mu.Unlock()
+
+	if r.FlowFinished != nil {
+		r.FlowFinished(finished)
+	}
+}
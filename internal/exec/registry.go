@@ -0,0 +1,108 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// ObserverOptions are passed to an ObserverFactory when creating an observer.
+type ObserverOptions struct {
+	Project *ilofile.Definition
+	RunID   string
+	// Output is where the observer writes, either stdout or a file.
+	Output io.Writer
+}
+
+// ObserverFactory creates an observer of a registered kind.
+type ObserverFactory func(opts ObserverOptions) (ExecutionObserver, error)
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]ObserverFactory)
+)
+
+// RegisterObserver makes a kind of observer available by name, for
+// NewRegisteredObserver. Packages usually register their observers in init.
+func RegisterObserver(kind string, factory ObserverFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[kind]; exists {
+		panic("observer kind registered twice: " + kind)
+	}
+	registry[kind] = factory
+}
+
+// RegisteredObservers returns the names of every registered kind of observer.
+func RegisteredObservers() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	kinds := make([]string, 0, len(registry))
+	for kind := range registry {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// ParseObserverSpec parses an observer specification of the form
+// <kind>[=<path>] into its kind and output path.
+func ParseObserverSpec(spec string) (kind string, path string) {
+	kind, path, _ = strings.Cut(spec, "=")
+	return kind, path
+}
+
+// NewRegisteredObserver creates an observer of a registered kind which
+// writes to the file at path, or to stdout if path is empty. An observer
+// writing to a file implements io.Closer, which closes the file.
+func NewRegisteredObserver(kind string, path string, opts ObserverOptions) (ExecutionObserver, error) {
+	registryMu.Lock()
+	factory, exists := registry[kind]
+	registryMu.Unlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown observer '%s', expected one of: %s",
+			kind, strings.Join(RegisteredObservers(), ", "))
+	}
+
+	if path == "" {
+		opts.Output = os.Stdout
+		return factory(opts)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create %s observer: %w", kind, err)
+	}
+	opts.Output = file
+
+	observer, err := factory(opts)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return fileObserver{ExecutionObserver: observer, file: file}, nil
+}
+
+// fileObserver closes the file an observer writes to once the observer
+// itself has been closed.
+type fileObserver struct {
+	ExecutionObserver
+	file *os.File
+}
+
+func (o fileObserver) Close() error {
+	var err error
+	if closer, ok := o.ExecutionObserver.(io.Closer); ok {
+		err = closer.Close()
+	}
+	return errors.Join(err, o.file.Close())
+}
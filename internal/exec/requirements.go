@@ -0,0 +1,125 @@
+package exec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// ToolProblem describes a tool needed by a project that the toolbox can't
+// provide, along with the command that would fix it.
+type ToolProblem struct {
+	Tool   string
+	Reason string
+	Fix    string
+}
+
+// MissingToolsError reports every tool problem found before running flows.
+type MissingToolsError struct {
+	Problems []ToolProblem
+}
+
+func (e MissingToolsError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d required tools are unavailable:", len(e.Problems))
+	for _, problem := range e.Problems {
+		fmt.Fprintf(&sb, "\n  $%s: %s\n    fix: %s", problem.Tool, problem.Reason, problem.Fix)
+	}
+	return sb.String()
+}
+
+// CheckTools checks that every tool referenced by the given flows, and
+// every tool required by the project, can be found in the toolbox at a
+// version satisfying the project's requirements.
+func CheckTools(project *ilofile.Definition, flows []ilofile.Flow, tb toolbox.Toolbox) error {
+	refs := make(map[string]bool)
+	for _, flow := range flows {
+		for _, ref := range flow.Tools {
+			refs[ref] = true
+		}
+	}
+
+	var pins, requires map[string]string
+	if project != nil {
+		pins = project.Tools
+		requires = project.Requires
+	}
+
+	for name := range requires {
+		if !referencesTool(refs, name) {
+			refs[name] = true
+		}
+	}
+
+	sortedRefs := make([]string, 0, len(refs))
+	for ref := range refs {
+		sortedRefs = append(sortedRefs, ref)
+	}
+	sort.Strings(sortedRefs)
+
+	var problems []ToolProblem
+	for _, ref := range sortedRefs {
+		if problem := checkTool(ref, pins, requires, tb); problem != nil {
+			problems = append(problems, *problem)
+		}
+	}
+
+	if len(problems) > 0 {
+		return MissingToolsError{Problems: problems}
+	}
+	return nil
+}
+
+func referencesTool(refs map[string]bool, name string) bool {
+	for ref := range refs {
+		if refName, _ := toolbox.ParseRef(ref); refName == name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTool(ref string, pins map[string]string, requires map[string]string, tb toolbox.Toolbox) *ToolProblem {
+	name, version := toolbox.ParseRef(ref)
+	if version == "" {
+		version = pins[name]
+	}
+
+	entry, err := tb.Resolve(name, version)
+	if err != nil {
+		fix := fmt.Sprintf("ilo tool add %s", name)
+		if version != "" {
+			fix = fmt.Sprintf("ilo tool add %s /path/to/%s", toolbox.Ref(name, version), name)
+		}
+		return &ToolProblem{Tool: ref, Reason: err.Error(), Fix: fix}
+	}
+
+	requirement, exists := requires[name]
+	if !exists {
+		return nil
+	}
+
+	// The requirement was validated when the project was parsed
+	constraint, _ := toolbox.ParseConstraint(requirement)
+	fix := fmt.Sprintf("ilo tool add %s@<version> /path/to/%s", name, name)
+
+	if entry.Version == "" {
+		return &ToolProblem{
+			Tool:   ref,
+			Reason: fmt.Sprintf("version of '%s' is unknown, but %s is required", entry.Path, constraint),
+			Fix:    fix,
+		}
+	}
+
+	if !constraint.Match(entry.Version) {
+		return &ToolProblem{
+			Tool:   ref,
+			Reason: fmt.Sprintf("version %s is registered, but %s is required", entry.Version, constraint),
+			Fix:    fix,
+		}
+	}
+	return nil
+}
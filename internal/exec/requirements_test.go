@@ -0,0 +1,74 @@
+package exec
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+func TestCheckTools(t *testing.T) {
+	var tb = toolbox.Toolbox{}
+	tb.Add("go", toolbox.Tool{Path: "/opt/go1.22/bin/go", Version: "1.22.1"})
+	tb.Add("go", toolbox.Tool{Path: "/opt/go1.20/bin/go", Version: "1.20.3"})
+	tb.Add("make", toolbox.Tool{Path: "/usr/bin/make"})
+
+	var tests = []struct {
+		tools    []string
+		pins     map[string]string
+		requires map[string]string
+		// expected lists the tool and fix of each problem, sorted by tool
+		expected [][2]string
+	}{
+		{[]string{"go", "make"}, nil, nil, nil},
+		{[]string{"go@1.20"}, nil, map[string]string{"go": ">=1.20, <2"}, nil},
+		{
+			[]string{"node", "go", "npm"}, nil, nil,
+			[][2]string{{"node", "ilo tool add node"}, {"npm", "ilo tool add npm"}},
+		},
+		{
+			[]string{"go"}, map[string]string{"go": "1.21"}, nil,
+			[][2]string{{"go", "ilo tool add go@1.21 /path/to/go"}},
+		},
+		{
+			[]string{"go@1.20"}, nil, map[string]string{"go": ">=1.21, <2", "python": ">=3"},
+			[][2]string{{"go@1.20", "ilo tool add go@<version> /path/to/go"}, {"python", "ilo tool add python"}},
+		},
+		{
+			[]string{"make"}, nil, map[string]string{"make": ">=4"},
+			[][2]string{{"make", "ilo tool add make@<version> /path/to/make"}},
+		},
+	}
+
+	for _, tc := range tests {
+		var project = &ilofile.Definition{Tools: tc.pins, Requires: tc.requires}
+		var flows = []ilofile.Flow{{Name: "build", Tools: tc.tools}}
+
+		var err = CheckTools(project, flows, tb)
+		if tc.expected == nil {
+			if err != nil {
+				t.Fatalf("got: %v, want: nil for %v", err, tc.tools)
+			}
+			continue
+		}
+
+		var missing MissingToolsError
+		if !errors.As(err, &missing) {
+			t.Fatalf("got: %v, want: MissingToolsError for %v", err, tc.tools)
+		}
+
+		var got [][2]string
+		for _, problem := range missing.Problems {
+			if problem.Reason == "" || !strings.Contains(err.Error(), problem.Fix) {
+				t.Fatalf("got: %+v, want: a reason and the fix in %q", problem, err)
+			}
+			got = append(got, [2]string{problem.Tool, problem.Fix})
+		}
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Fatalf("got: %v, want: %v", got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+)
+
+// Trigger is what started a run.
+type Trigger string
+
+const (
+	TriggerCLI      Trigger = "cli"
+	TriggerAPI      Trigger = "api"
+	TriggerSchedule Trigger = "schedule"
+)
+
+// Record is the result of running a single flow.
+type Record struct {
+	RunID       string      `json:"run"`
+	Project     string      `json:"project"`
+	ProjectName string      `json:"project_name,omitempty"`
+	Flow        string      `json:"flow"`
+	Trigger     Trigger     `json:"trigger"`
+	Status      exec.Status `json:"status"`
+	Start       time.Time   `json:"start"`
+	End         time.Time   `json:"end"`
+	Steps       []Step      `json:"steps"`
+	// Error is why a skipped flow didn't run.
+	Error string `json:"error,omitempty"`
+}
+
+// Step is the result of a single step in a flow.
+type Step struct {
+	Text       string      `json:"text"`
+	Status     exec.Status `json:"status"`
+	DurationMs int64       `json:"duration_ms"`
+	ExitCode   int         `json:"exit_code"`
+	Error      string      `json:"error,omitempty"`
+}
+
+func (r Record) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Store is a history of runs, kept as a file with one JSON record per line.
+// Appending and pruning take a lock, so the CLI and the server can both
+// write to the same history at once.
+type Store struct {
+	Path string
+}
+
+// NewConfigStore returns the history in the user's ilo config directory.
+func NewConfigStore() Store {
+	configPath, err := os.UserConfigDir()
+	if err != nil {
+		panic(err)
+	}
+	return Store{Path: filepath.Join(configPath, "ilo", "history.jsonl")}
+}
+
+// Append adds records to the history.
+func (s Store) Append(records ...Record) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("record history: %w", err)
+		}
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return fmt.Errorf("record history: %w", err)
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("record history: %w", err)
+	}
+	defer file.Close()
+
+	// A single write keeps records from concurrent writers apart
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("record history: %w", err)
+	}
+	return nil
+}
+
+// Filter selects records from the history. Empty fields match any record.
+type Filter struct {
+	Project string
+	Flow    string
+	Status  exec.Status
+	Trigger Trigger
+	Since   time.Time
+}
+
+func (f Filter) Match(record Record) bool {
+	return (f.Project == "" || record.Project == f.Project) &&
+		(f.Flow == "" || record.Flow == f.Flow) &&
+		(f.Status == "" || record.Status == f.Status) &&
+		(f.Trigger == "" || record.Trigger == f.Trigger) &&
+		(f.Since.IsZero() || !record.Start.Before(f.Since))
+}
+
+// Query returns every record matching filter, oldest first. Lines which
+// can't be read, such as one cut short by a crash, are skipped.
+func (s Store) Query(filter Filter) ([]Record, error) {
+	file, err := os.Open(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if filter.Match(record) {
+			records = append(records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Start.Before(records[j].Start)
+	})
+	return records, nil
+}
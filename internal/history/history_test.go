@@ -0,0 +1,140 @@
+package history
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+)
+
+func TestSummarize(t *testing.T) {
+	var store = Store{Path: t.TempDir() + "/history.jsonl"}
+	var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var statuses = []exec.Status{
+		exec.StatusPassed, exec.StatusPassed, exec.StatusFailed, exec.StatusPassed, exec.StatusPassed,
+	}
+	var durations = []time.Duration{10, 10, 1, 20, 20}
+
+	for i, status := range statuses {
+		var runStart = start.Add(time.Duration(i) * time.Hour)
+		var err = store.Append(
+			Record{Project: "/a/ilo.yml", Flow: "build", Status: status, Start: runStart,
+				End: runStart.Add(durations[i] * time.Second)},
+			Record{Project: "/a/ilo.yml", Flow: "test", Status: exec.StatusPassed, Start: runStart, End: runStart},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := store.Query(Filter{Flow: "build"})
+	if err != nil || len(records) != 5 {
+		t.Fatalf("got: %d records, %v, want: 5, nil", len(records), err)
+	}
+
+	var stats = Summarize(records)
+	if len(stats) != 1 {
+		t.Fatalf("got: %d flows, want: 1", len(stats))
+	}
+
+	var build = stats[0]
+	if build.Runs != 5 || build.Passed != 4 || build.FlakeRate != 0.5 {
+		t.Fatalf("got: %d runs, %d passed, flake rate %v, want: 5, 4, 0.5", build.Runs, build.Passed, build.FlakeRate)
+	}
+	if build.MeanDuration != 15*time.Second || build.Trend() != 1 {
+		t.Fatalf("got: mean %s, trend %v, want: 15s, 1", build.MeanDuration, build.Trend())
+	}
+}
+
+func TestPrune(t *testing.T) {
+	var store = Store{Path: t.TempDir() + "/history.jsonl"}
+	var now = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	removed, err := store.Prune(Retention{MaxAge: "30d", MaxSize: "0"}, now)
+	if removed != 0 || err != nil {
+		t.Fatalf("got: %d, %v, want: 0, nil for a missing history", removed, err)
+	}
+
+	for days := 45; days >= 5; days -= 10 {
+		var start = now.AddDate(0, 0, -days)
+		if err := store.Append(Record{Flow: "build", Start: start, End: start}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err = store.Prune(Retention{MaxAge: "30d", MaxSize: "0"}, now)
+	if removed != 2 || err != nil {
+		t.Fatalf("got: %d, %v, want: 2, nil", removed, err)
+	}
+	records, err := store.Query(Filter{})
+	if err != nil || len(records) != 3 || !records[0].Start.Equal(now.AddDate(0, 0, -25)) {
+		t.Fatalf("got: %v, %v, want: the last 3 records", records, err)
+	}
+
+	removed, err = store.Prune(Retention{MaxAge: "30d", MaxSize: "0"}, now)
+	if removed != 0 || err != nil {
+		t.Fatalf("got: %d, %v, want: 0, nil once pruned", removed, err)
+	}
+
+	info, err := os.Stat(store.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err = store.Prune(Retention{MaxAge: "0", MaxSize: strconv.FormatInt(info.Size()-1, 10)}, now)
+	if removed != 1 || err != nil {
+		t.Fatalf("got: %d, %v, want: 1, nil", removed, err)
+	}
+	records, err = store.Query(Filter{})
+	if err != nil || len(records) != 2 || !records[1].Start.Equal(now.AddDate(0, 0, -5)) {
+		t.Fatalf("got: %v, %v, want: the newest 2 records", records, err)
+	}
+}
+
+func TestPruneWhileAppending(t *testing.T) {
+	var store = Store{Path: t.TempDir() + "/history.jsonl"}
+	var now = time.Now()
+
+	const appends = 200
+	var done = make(chan error)
+	go func() {
+		for i := 0; i < appends; i++ {
+			var start = now.Add(time.Duration(i) * time.Second)
+			if err := store.Append(Record{Flow: "build", RunID: strconv.Itoa(i), Start: start, End: start}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	// A large old record makes every prune rewrite the history, and is the
+	// only record removed
+	var old = now.AddDate(0, 0, -60)
+	var large = strings.Repeat("x", 100*1024)
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, err := store.Query(Filter{Flow: "build"})
+			if err != nil || len(records) != appends {
+				t.Fatalf("got: %d records, %v, want: %d, nil", len(records), err, appends)
+			}
+			return
+		default:
+		}
+
+		if err := store.Append(Record{Flow: "old", Start: old, End: old, Error: large}); err != nil {
+			t.Fatal(err)
+		}
+		removed, err := store.Prune(Retention{MaxAge: "30d", MaxSize: "64KB"}, now)
+		if removed != 1 || err != nil {
+			t.Fatalf("got: %d, %v, want: 1, nil", removed, err)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lock takes a lock on a file next to the history, waiting for any other
+// process or goroutine holding it, and returns a function which releases
+// it. Appending and pruning both hold the lock, so that records appended
+// while the history is being rewritten aren't lost.
+func (s Store) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.Path), os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(s.Path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Closing the file releases the lock
+	return func() { file.Close() }, nil
+}
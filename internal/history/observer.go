@@ -0,0 +1,72 @@
+package history
+
+import (
+	"sync"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// Observer records every flow it observes to a history store as soon as
+// the flow finishes.
+type Observer struct {
+	*exec.Recorder
+	store   Store
+	project *ilofile.Definition
+	trigger Trigger
+
+	mu  sync.Mutex
+	err error
+}
+
+func NewObserver(store Store, project *ilofile.Definition, trigger Trigger) *Observer {
+	o := &Observer{
+		Recorder: exec.NewRecorder(),
+		store:    store,
+		project:  project,
+		trigger:  trigger,
+	}
+	o.Recorder.FlowFinished = o.record
+	return o
+}
+
+func (o *Observer) record(flow exec.FlowRecord) {
+	record := Record{
+		RunID:   flow.RunID,
+		Flow:    flow.Name,
+		Trigger: o.trigger,
+		Status:  flow.Status,
+		Start:   flow.Start,
+		End:     flow.End,
+		Error:   flow.Error,
+	}
+	if o.project != nil {
+		record.Project = o.project.Path
+		record.ProjectName = o.project.Name
+	}
+
+	for _, step := range flow.Steps {
+		record.Steps = append(record.Steps, Step{
+			Text:       step.Text,
+			Status:     step.Status,
+			DurationMs: step.Duration().Milliseconds(),
+			ExitCode:   step.ExitCode,
+			Error:      step.Error,
+		})
+	}
+
+	if err := o.store.Append(record); err != nil {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.err == nil {
+			o.err = err
+		}
+	}
+}
+
+// Close reports the first error recording a flow, if any.
+func (o *Observer) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.err
+}
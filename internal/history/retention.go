@@ -0,0 +1,164 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/runlog"
+)
+
+const (
+	DefaultMaxAge  = "90d"
+	DefaultMaxSize = "10MB"
+)
+
+// Retention limits which records are kept in the history, and is saved as
+// history.yml in the user's ilo config directory. Empty settings use the
+// defaults, and "0" removes a limit.
+type Retention struct {
+	// MaxAge removes records of runs that started longer ago than this.
+	MaxAge string `yaml:"max_age,omitempty"`
+	// MaxSize removes the oldest records while the history takes up more
+	// than this.
+	MaxSize string `yaml:"max_size,omitempty"`
+}
+
+// LoadRetention loads the retention settings, filling in defaults.
+func LoadRetention(loader provide.Loader[Retention]) (Retention, error) {
+	retention, err := loader.Load("history", provide.YamlUnmarshal[Retention])
+	if err != nil {
+		return Retention{}, fmt.Errorf("load history retention: %w", err)
+	}
+
+	if retention.MaxAge == "" {
+		retention.MaxAge = DefaultMaxAge
+	}
+	if retention.MaxSize == "" {
+		retention.MaxSize = DefaultMaxSize
+	}
+	return *retention, nil
+}
+
+// Prune removes records of runs which are too old, then the oldest records
+// until the history takes up less than three quarters of the maximum size,
+// so that it isn't rewritten after every run. It returns the number of
+// records removed. The history is only read in full when its size or its
+// first record shows that something needs removing.
+func (s Store) Prune(retention Retention, now time.Time) (int, error) {
+	maxAge, err := runlog.ParseAge(retention.MaxAge)
+	if err != nil {
+		return 0, err
+	}
+	maxSize, err := runlog.ParseSize(retention.MaxSize)
+	if err != nil {
+		return 0, err
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+	defer unlock()
+
+	file, err := os.Open(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+
+	tooBig := maxSize > 0 && info.Size() > maxSize
+	if !tooBig && !(maxAge > 0 && s.oldestBefore(file, now.Add(-maxAge))) {
+		return 0, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+
+	var lines [][]byte
+	var kept int64
+	total := 0
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			total += 1
+		}
+
+		var record Record
+		if len(line) > 0 && json.Unmarshal(line, &record) == nil && !(maxAge > 0 && now.Sub(record.Start) > maxAge) {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			lines = append(lines, line)
+			kept += int64(len(line))
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return 0, fmt.Errorf("prune history: %w", err)
+		}
+	}
+	for maxSize > 0 && kept > maxSize*3/4 && len(lines) > 1 {
+		kept -= int64(len(lines[0]))
+		lines = lines[1:]
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(s.Path), ".history-*.jsonl")
+	if err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+	if err := temp.Chmod(0o644); err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+
+	writer := bufio.NewWriter(temp)
+	for _, line := range lines {
+		writer.Write(line)
+	}
+	if err := writer.Flush(); err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+	file.Close()
+	if err := os.Rename(temp.Name(), s.Path); err != nil {
+		return 0, fmt.Errorf("prune history: %w", err)
+	}
+
+	return total - len(lines), nil
+}
+
+// oldestBefore reports whether the first readable record in file started
+// before cutoff. Records are appended as runs finish, so the first is the
+// oldest, give or take runs which overlapped it.
+func (s Store) oldestBefore(file *os.File, cutoff time.Time) bool {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			return record.Start.Before(cutoff)
+		}
+	}
+	return false
+}
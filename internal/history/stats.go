@@ -0,0 +1,122 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+)
+
+// trendWindow is the number of runs compared when working out a trend.
+const trendWindow = 5
+
+// FlowStats summarises the history of a single flow.
+type FlowStats struct {
+	Project     string `json:"project"`
+	ProjectName string `json:"project_name,omitempty"`
+	Flow        string `json:"flow"`
+	Runs        int    `json:"runs"`
+	Passed      int    `json:"passed"`
+	Failed      int    `json:"failed"`
+	// FlakeRate is the fraction of runs whose status differed from the run
+	// before, so a flow which alternates between passing and failing has a
+	// flake rate of 1, and one which always passes or always fails has 0.
+	FlakeRate float64 `json:"flake_rate"`
+	// MeanDuration is the mean duration of every passing run.
+	MeanDuration time.Duration `json:"mean_duration_ns"`
+	// RecentDuration and PreviousDuration are the mean durations of up to
+	// the last five passing runs and of as many before them.
+	// PreviousDuration is zero if there aren't enough runs to compare.
+	RecentDuration   time.Duration `json:"recent_duration_ns"`
+	PreviousDuration time.Duration `json:"previous_duration_ns"`
+	Last             time.Time     `json:"last"`
+}
+
+// PassRate returns the fraction of runs which passed.
+func (s FlowStats) PassRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Runs)
+}
+
+// Trend returns how much slower recent passing runs have been than the
+// ones before them, e.g. 0.1 for 10% slower, or 0 if there aren't enough
+// runs to tell.
+func (s FlowStats) Trend() float64 {
+	if s.PreviousDuration == 0 {
+		return 0
+	}
+	return float64(s.RecentDuration-s.PreviousDuration) / float64(s.PreviousDuration)
+}
+
+func meanDuration(records []Record) time.Duration {
+	if len(records) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, record := range records {
+		total += record.Duration()
+	}
+	return total / time.Duration(len(records))
+}
+
+// Summarize works out statistics for each flow in records, which must be
+// oldest first as returned by Query. Flows are ordered by project and name.
+func Summarize(records []Record) []FlowStats {
+	type flowKey struct{ project, flow string }
+	byFlow := make(map[flowKey][]Record)
+	for _, record := range records {
+		// A skipped flow didn't run, so says nothing about the flow itself
+		if record.Status == exec.StatusSkipped {
+			continue
+		}
+		key := flowKey{record.Project, record.Flow}
+		byFlow[key] = append(byFlow[key], record)
+	}
+
+	var stats []FlowStats
+	for key, runs := range byFlow {
+		flow := FlowStats{Project: key.project, Flow: key.flow, Runs: len(runs)}
+
+		var passed []Record
+		flips := 0
+		for i, run := range runs {
+			if run.Status == exec.StatusPassed {
+				flow.Passed += 1
+				passed = append(passed, run)
+			} else {
+				flow.Failed += 1
+			}
+			if i > 0 && run.Status != runs[i-1].Status {
+				flips += 1
+			}
+			flow.Last = run.Start
+			flow.ProjectName = run.ProjectName
+		}
+
+		if len(runs) > 1 {
+			flow.FlakeRate = float64(flips) / float64(len(runs)-1)
+		}
+
+		flow.MeanDuration = meanDuration(passed)
+		flow.RecentDuration = meanDuration(passed[max(len(passed)-trendWindow, 0):])
+
+		// Compare equal numbers of runs, using fewer if there aren't many
+		if window := min(trendWindow, len(passed)/2); window > 0 {
+			flow.RecentDuration = meanDuration(passed[len(passed)-window:])
+			flow.PreviousDuration = meanDuration(passed[len(passed)-2*window : len(passed)-window])
+		}
+
+		stats = append(stats, flow)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Project != stats[j].Project {
+			return stats[i].Project < stats[j].Project
+		}
+		return stats[i].Flow < stats[j].Flow
+	})
+	return stats
+}
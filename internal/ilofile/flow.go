@@ -1,5 +1,14 @@
 package ilofile
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fourls/ilo/internal/data"
+	"github.com/fourls/ilo/internal/data/toolbox"
+)
+
 type Step interface {
 	StepType() StepType
 	String() string
@@ -13,6 +22,12 @@ type EchoFlowStep interface {
 	Message() string
 }
 
+// LocatedStep is implemented by steps which know the line of the project
+// definition file they were defined on.
+type LocatedStep interface {
+	Line() int
+}
+
 type StepType int
 
 const (
@@ -21,14 +36,125 @@ const (
 )
 
 type Flow struct {
-	Name    string
-	Dir     string
-	Steps   []Step
-	Project *Definition
+	Name string
+	// Description is a short summary of what the flow does, if given.
+	Description string
+	Dir         string
+	Steps       []Step
+	Project     *Definition
+	// Tools lists every tool referenced by the flow's steps, such as go or
+	// go@1.22 for `$go` and `$go@1.22`.
+	Tools []string
+	// Needs lists the flows which must pass before this flow runs.
+	Needs []string
+	// Schedules are when the automation server runs the flow, once the
+	// project is registered with it.
+	Schedules []FlowSchedule
+}
+
+// FlowSchedule is a schedule declared for a flow, along with what to do
+// about missed and overlapping runs.
+type FlowSchedule struct {
+	data.Schedule
+	CatchUp data.CatchUp
+	Overlap data.Overlap
+	// ID names the schedule, if given, so that the server keeps its state
+	// when its expression or time zone changes.
+	ID string
+}
+
+// NextRun returns the earliest time after t that one of the flow's
+// schedules is due, or the zero time if it has none.
+func (f Flow) NextRun(t time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range f.Schedules {
+		due := schedule.NextAfter(t)
+		if !due.IsZero() && (next.IsZero() || due.Before(next)) {
+			next = due
+		}
+	}
+	return next
+}
+
+// FailedNeed returns the first flow needed by f which hasn't passed,
+// according to passed, or an empty string if f can run.
+func (f Flow) FailedNeed(passed map[string]bool) string {
+	for _, need := range f.Needs {
+		if !passed[need] {
+			return need
+		}
+	}
+	return ""
 }
 
 type Definition struct {
 	Name  string
 	Path  string
 	Flows map[string]Flow
+	// Tools pins tools referenced by the project to a version, by tool name.
+	Tools map[string]string
+	// Toolbox holds tools declared by the project itself, which take
+	// precedence over the user and system toolboxes.
+	Toolbox toolbox.Toolbox
+	// Requires constrains the versions of tools the project can use, by tool
+	// name, e.g. ">=1.21, <2".
+	Requires map[string]string
+}
+
+// Plan returns the named flows along with every flow they need, ordered so
+// that each flow comes after the flows it needs. Each flow appears once,
+// even if it is needed by several others.
+func (d *Definition) Plan(names ...string) ([]Flow, error) {
+	var plan []Flow
+	planned := make(map[string]bool)
+	visiting := make(map[string]bool)
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if planned[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("flow '%s' needs itself: %s", name, formatCycle(append(path, name)))
+		}
+
+		flow, exists := d.Flows[name]
+		if !exists {
+			if len(path) > 0 {
+				return fmt.Errorf("flow '%s' needs flow '%s', which doesn't exist", path[len(path)-1], name)
+			}
+			return fmt.Errorf("no flow '%s' exists", name)
+		}
+
+		visiting[name] = true
+		for _, need := range flow.Needs {
+			if err := visit(need, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+
+		planned[name] = true
+		plan = append(plan, flow)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+func formatCycle(path []string) string {
+	start := 0
+	for i, name := range path {
+		if name == path[len(path)-1] {
+			start = i
+			break
+		}
+	}
+
+	return strings.Join(path[start:], " -> ")
 }
@@ -0,0 +1,72 @@
+package ilofile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	var def = Definition{Flows: map[string]Flow{
+		"build":   {Name: "build"},
+		"lint":    {Name: "lint"},
+		"test":    {Name: "test", Needs: []string{"build"}},
+		"package": {Name: "package", Needs: []string{"build"}},
+		"release": {Name: "release", Needs: []string{"test", "package", "build"}},
+		"loop":    {Name: "loop", Needs: []string{"cycle"}},
+		"cycle":   {Name: "cycle", Needs: []string{"test", "loop"}},
+		"broken":  {Name: "broken", Needs: []string{"missing"}},
+	}}
+
+	var tests = []struct {
+		names    []string
+		expected []string
+		err      string
+	}{
+		{[]string{"build"}, []string{"build"}, ""},
+		{[]string{"release"}, []string{"build", "test", "package", "release"}, ""},
+		{[]string{"lint", "test", "build", "release"}, []string{"lint", "build", "test", "package", "release"}, ""},
+		{[]string{"test", "test"}, []string{"build", "test"}, ""},
+		{[]string{"loop"}, nil, "flow 'loop' needs itself: loop -> cycle -> loop"},
+		{[]string{"broken"}, nil, "flow 'broken' needs flow 'missing', which doesn't exist"},
+		{[]string{"build", "missing"}, nil, "no flow 'missing' exists"},
+	}
+
+	for _, tc := range tests {
+		plan, err := def.Plan(tc.names...)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("Plan(%v) got: %v, want: error containing %s", tc.names, err, tc.err)
+			}
+			continue
+		}
+
+		var names []string
+		for _, flow := range plan {
+			names = append(names, flow.Name)
+		}
+		if err != nil || !reflect.DeepEqual(names, tc.expected) {
+			t.Fatalf("Plan(%v) got: %v, %v, want: %v, nil", tc.names, names, err, tc.expected)
+		}
+	}
+}
+
+func TestFailedNeed(t *testing.T) {
+	var flow = Flow{Name: "release", Needs: []string{"test", "package"}}
+
+	var tests = []struct {
+		passed   map[string]bool
+		expected string
+	}{
+		{map[string]bool{"test": true, "package": true}, ""},
+		{map[string]bool{"test": true, "package": false}, "package"},
+		{map[string]bool{"test": true}, "package"},
+		{map[string]bool{}, "test"},
+	}
+
+	for _, tc := range tests {
+		if got := flow.FailedNeed(tc.passed); got != tc.expected {
+			t.Fatalf("got: %q, want: %q for %v", got, tc.expected, tc.passed)
+		}
+	}
+}
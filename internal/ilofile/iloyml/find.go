@@ -0,0 +1,68 @@
+package iloyml
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileNames are the names a project definition file can have, in order of
+// preference.
+var FileNames = []string{"ilo.yml", "ilo.yaml", ".ilo.yml"}
+
+// ErrNotFound is returned when no project definition file can be found.
+var ErrNotFound = errors.New("no project definition found")
+
+// Locate returns the absolute path of the project definition file at path.
+// If path is a directory, it and then its parents are searched for a
+// definition file, stopping at the root of the git repository or the
+// filesystem.
+func Locate(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	stat, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("find project '%s': no such file or directory", path)
+	} else if err != nil {
+		return "", fmt.Errorf("find project '%s': %w", path, err)
+	}
+
+	if !stat.IsDir() {
+		return path, nil
+	}
+	return find(path)
+}
+
+func find(dir string) (string, error) {
+	start := dir
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			stat, err := os.Stat(path)
+			if err == nil && !stat.IsDir() {
+				return path, nil
+			} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return "", fmt.Errorf("find project '%s': %w", path, err)
+			}
+		}
+
+		// Like git, don't look outside the repository the search started in
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	return "", fmt.Errorf("find project in '%s' or its parents: %w (looked for %s)",
+		start, ErrNotFound, strings.Join(FileNames, ", "))
+}
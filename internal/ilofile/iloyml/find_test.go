@@ -0,0 +1,58 @@
+package iloyml
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	var root = t.TempDir()
+	var write = func(path string) string {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("flows: {}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	var outer = write("ilo.yml")
+	var repo = write("repo/.ilo.yml")
+	var preferred = write("repo/nested/ilo.yaml")
+	write("repo/nested/.ilo.yml")
+	write("repo/.git/HEAD")
+	write("other/.git/HEAD")
+	os.MkdirAll(filepath.Join(root, "repo/nested/deep/er"), 0o755)
+	os.MkdirAll(filepath.Join(root, "repo/sub"), 0o755)
+	os.MkdirAll(filepath.Join(root, "other/sub"), 0o755)
+
+	var tests = []struct {
+		path     string
+		expected string
+	}{
+		{root, outer},
+		{filepath.Join(root, "repo/nested/deep/er"), preferred},
+		{filepath.Join(root, "repo/sub"), repo},
+		{repo, repo},
+	}
+
+	for _, tc := range tests {
+		got, err := Locate(tc.path)
+		if err != nil || got != tc.expected {
+			t.Fatalf("Locate(%s) got: %s, %v, want: %s", tc.path, got, err, tc.expected)
+		}
+	}
+
+	// The search stops at the root of the repository it started in
+	if _, err := Locate(filepath.Join(root, "other/sub")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got: %v, want: %v", err, ErrNotFound)
+	}
+
+	if _, err := Locate(filepath.Join(root, "missing")); err == nil {
+		t.Fatalf("got: nil, want: error for missing path")
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"unicode"
 
+	"github.com/fourls/ilo/internal/data"
+	"github.com/fourls/ilo/internal/data/toolbox"
 	"github.com/fourls/ilo/internal/ilofile"
 	"gopkg.in/yaml.v3"
 )
@@ -14,17 +18,69 @@ import (
 type yamlStepDef struct {
 	Echo string
 	Run  string
+	// Line is where the step is defined in the file.
+	Line int `yaml:"-"`
+}
+
+func (d *yamlStepDef) UnmarshalYAML(node *yaml.Node) error {
+	type stepFields yamlStepDef
+	if err := node.Decode((*stepFields)(d)); err != nil {
+		return err
+	}
+	d.Line = node.Line
+	return nil
+}
+
+// yamlFlowDef is a flow, written either as a list of steps or as a mapping
+// with a description and steps.
+type yamlFlowDef struct {
+	Description string
+	Needs       []string
+	Schedule    yaml.Node
+	Steps       []yamlStepDef
+}
+
+// yamlScheduleDef is a cron expression for a flow, written either on its
+// own or as a mapping with a time zone.
+type yamlScheduleDef struct {
+	Id       string
+	Cron     string
+	Timezone string
+	Catchup  string
+	Overlap  string
+}
+
+func (d *yamlScheduleDef) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&d.Cron)
+	}
+
+	type scheduleFields yamlScheduleDef
+	return node.Decode((*scheduleFields)(d))
+}
+
+func (d *yamlFlowDef) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&d.Steps)
+	}
+
+	type flowFields yamlFlowDef
+	return node.Decode((*flowFields)(d))
 }
 
 type yamlProjDef struct {
-	Name  string
-	Flows map[string][]yamlStepDef
+	Name     string
+	Versions map[string]string
+	Tools    map[string]yaml.Node
+	Requires map[string]string
+	Flows    map[string]yamlFlowDef
 }
 
 type step struct {
 	text     string
 	args     []string
 	stepType ilofile.StepType
+	line     int
 }
 
 func (s step) StepType() ilofile.StepType {
@@ -43,6 +99,10 @@ func (s step) String() string {
 	return s.text
 }
 
+func (s step) Line() int {
+	return s.line
+}
+
 func New(path string) (*ilofile.Definition, error) {
 	bytes, err := os.ReadFile(path)
 	if err != nil {
@@ -67,18 +127,37 @@ func parseProjectDefinitionYaml(data []byte, project *ilofile.Definition) error
 	}
 
 	project.Name = yml.Name
+	if err := parseTools(yml, project); err != nil {
+		return err
+	}
+
+	project.Requires = yml.Requires
+	for toolName, constraint := range project.Requires {
+		if _, err := toolbox.ParseConstraint(constraint); err != nil {
+			return fmt.Errorf("parse requirement for tool '%s': %w", toolName, err)
+		}
+	}
+
 	project.Flows = make(map[string]ilofile.Flow, len(yml.Flows))
 	projectDir := filepath.Dir(project.Path)
 
-	for flowName, flowCmds := range yml.Flows {
+	for flowName, flowDef := range yml.Flows {
 		flow := ilofile.Flow{
-			Name:    flowName,
-			Steps:   make([]ilofile.Step, len(flowCmds)),
-			Project: project,
-			Dir:     projectDir,
+			Name:        flowName,
+			Description: flowDef.Description,
+			Needs:       flowDef.Needs,
+			Steps:       make([]ilofile.Step, len(flowDef.Steps)),
+			Project:     project,
+			Dir:         projectDir,
 		}
 
-		for i, line := range flowCmds {
+		schedules, err := parseSchedules(flowDef.Schedule)
+		if err != nil {
+			return fmt.Errorf("parse '%s' schedule: %w", flowName, err)
+		}
+		flow.Schedules = schedules
+
+		for i, line := range flowDef.Steps {
 			var stepType ilofile.StepType
 			switch {
 			case line.Run != "" && line.Echo == "":
@@ -91,6 +170,7 @@ func parseProjectDefinitionYaml(data []byte, project *ilofile.Definition) error
 
 			step := step{
 				stepType: stepType,
+				line:     line.Line,
 			}
 
 			switch step.stepType {
@@ -102,14 +182,136 @@ func parseProjectDefinitionYaml(data []byte, project *ilofile.Definition) error
 			}
 
 			flow.Steps[i] = step
+
+			if len(step.args) > 0 && strings.HasPrefix(step.args[0], "$") {
+				flow.Tools = appendUnique(flow.Tools, step.args[0][1:])
+			}
 		}
 
 		project.Flows[flowName] = flow
 	}
 
+	// Check that every flow's needs exist and don't form a cycle, in order
+	// so that the same cycle is always reported
+	flowNames := make([]string, 0, len(project.Flows))
+	for flowName := range project.Flows {
+		flowNames = append(flowNames, flowName)
+	}
+	slices.Sort(flowNames)
+
+	for _, flowName := range flowNames {
+		if _, err := project.Plan(flowName); err != nil {
+			return fmt.Errorf("parse '%s' needs: %w", flowName, err)
+		}
+	}
+
 	return nil
 }
 
+// parseTools reads the tools map of a project, in which a version string
+// pins the version of a tool and a mapping declares a project-local tool.
+// Versions can also be pinned under versions, which is read as an alias.
+func parseTools(yml yamlProjDef, project *ilofile.Definition) error {
+	project.Tools = make(map[string]string)
+	project.Toolbox = make(toolbox.Toolbox)
+
+	for toolName, version := range yml.Versions {
+		if version == "" {
+			return fmt.Errorf("parse version of tool '%s': no version specified", toolName)
+		}
+		project.Tools[toolName] = version
+	}
+
+	for toolName, node := range yml.Tools {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			if node.Value == "" {
+				return fmt.Errorf("parse tool '%s': no version specified", toolName)
+			}
+			if _, exists := yml.Versions[toolName]; exists {
+				return fmt.Errorf("parse tool '%s': version pinned under both tools and versions", toolName)
+			}
+			project.Tools[toolName] = node.Value
+		case yaml.MappingNode:
+			var tool toolbox.Tool
+			if err := node.Decode(&tool); err != nil {
+				return fmt.Errorf("parse tool '%s': %w", toolName, err)
+			}
+			if tool.Path == "" {
+				return fmt.Errorf("parse tool '%s': no path specified", toolName)
+			}
+			project.Toolbox[toolName] = tool
+		default:
+			return fmt.Errorf("parse tool '%s': expected a version or a tool definition", toolName)
+		}
+	}
+
+	return nil
+}
+
+// parseSchedules reads the schedule of a flow, which is a cron expression,
+// a mapping with a cron expression, time zone and policies, or a list of
+// either.
+func parseSchedules(node yaml.Node) ([]ilofile.FlowSchedule, error) {
+	var defs []yamlScheduleDef
+	switch node.Kind {
+	case 0:
+		return nil, nil
+	case yaml.SequenceNode:
+		if err := node.Decode(&defs); err != nil {
+			return nil, err
+		}
+	default:
+		defs = make([]yamlScheduleDef, 1)
+		if err := node.Decode(&defs[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	schedules := make([]ilofile.FlowSchedule, len(defs))
+	seen := make(map[string]bool)
+	for i, def := range defs {
+		if def.Cron == "" {
+			return nil, errors.New("no cron expression specified")
+		}
+
+		schedule, err := data.ParseSchedule(def.Cron, def.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		catchUp, err := data.ParseCatchUp(def.Catchup)
+		if err != nil {
+			return nil, err
+		}
+		overlap, err := data.ParseOverlap(def.Overlap)
+		if err != nil {
+			return nil, err
+		}
+		schedules[i] = ilofile.FlowSchedule{Schedule: schedule, CatchUp: catchUp, Overlap: overlap, ID: def.Id}
+
+		// The server tells schedules apart by their ID, or else by when they run
+		key := "cron\x00" + schedule.String() + "\x00" + schedule.Location().String()
+		if def.Id != "" {
+			key = "id\x00" + def.Id
+		}
+		if seen[key] {
+			if def.Id != "" {
+				return nil, fmt.Errorf("schedule id '%s' is used twice", def.Id)
+			}
+			return nil, fmt.Errorf("schedule '%s' is declared twice, give them different ids", def.Cron)
+		}
+		seen[key] = true
+	}
+	return schedules, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
 func parseArgsString(line string, out *[]string) error {
 	const (
 		None = iota
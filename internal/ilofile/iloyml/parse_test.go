@@ -2,6 +2,7 @@ package iloyml
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/fourls/ilo/internal/ilofile"
@@ -15,7 +16,9 @@ flows:
     - run: cmd -abc "this is a foo text"
     - echo: Finishing foo
   bar:
-    - echo: Doing "bar" now`)
+    description: Does bar
+    steps:
+      - echo: Doing "bar" now`)
 
 	var def ilofile.Definition
 	if err := parseProjectDefinitionYaml(data, &def); err != nil {
@@ -26,12 +29,16 @@ flows:
 		t.Fatalf("got: %d flows, want: 2 flows", len(def.Flows))
 	}
 
+	if bar := def.Flows["bar"]; bar.Description != "Does bar" || len(bar.Steps) != 1 {
+		t.Fatalf("got: %q with %d steps, want: \"Does bar\" with 1 step", bar.Description, len(bar.Steps))
+	}
+
 	var flow = def.Flows["foo"]
 	if flow.Name != "foo" || len(flow.Steps) != 3 {
 		t.Fatalf("got: %d steps in flow, want: 3 steps in flow", len(flow.Steps))
 	}
 
-	var expectedStep = step{stepType: ilofile.StepEchoMessage, text: "Starting foo"}
+	var expectedStep = step{stepType: ilofile.StepEchoMessage, text: "Starting foo", line: 4}
 
 	if !reflect.DeepEqual(flow.Steps[0], expectedStep) {
 		t.Fatalf("got: %s, want: %s", flow.Steps[0], expectedStep)
@@ -41,6 +48,7 @@ flows:
 		stepType: ilofile.StepRunProgram,
 		text:     "cmd -abc \"this is a foo text\"",
 		args:     []string{"cmd", "-abc", "this is a foo text"},
+		line:     5,
 	}
 
 	if !reflect.DeepEqual(flow.Steps[1], expectedStep) {
@@ -91,3 +99,229 @@ func TestParseArgsString(t *testing.T) {
 	}
 
 }
+
+func TestParseToolReferences(t *testing.T) {
+	var data = []byte(`
+requires:
+  go: ">=1.21, <2"
+flows:
+  foo:
+    - run: $go build ./...
+    - run: $go@1.22 test ./...
+    - run: $go vet ./...
+    - run: bash -c true`)
+
+	var def ilofile.Definition
+	if err := parseProjectDefinitionYaml(data, &def); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var expected = []string{"go", "go@1.22"}
+	if !reflect.DeepEqual(def.Flows["foo"].Tools, expected) {
+		t.Fatalf("got: %v, want: %v", def.Flows["foo"].Tools, expected)
+	}
+
+	data = []byte(`
+requires:
+  go: ">="
+flows: {}`)
+
+	if err := parseProjectDefinitionYaml(data, &def); err == nil {
+		t.Fatalf("got: nil, want: error for invalid version constraint")
+	}
+}
+
+func TestParseTools(t *testing.T) {
+	var tests = []struct {
+		data    string
+		pins    map[string]string
+		toolbox []string
+		err     string
+	}{
+		{"tools:\n  go: \"1.22\"\nflows: {}", map[string]string{"go": "1.22"}, nil, ""},
+		{"versions:\n  go: \"1.22\"\nflows: {}", map[string]string{"go": "1.22"}, nil, ""},
+		{"tools:\n  go: \"1.22\"\n  node:\n    path: ./node_modules/.bin/node\nflows: {}", map[string]string{"go": "1.22"}, []string{"node"}, ""},
+		{"tools:\n  go: \"1.22\"\nversions:\n  go: \"1.21\"\nflows: {}", nil, nil, "pinned under both"},
+		{"tools:\n  go: \"\"\nflows: {}", nil, nil, "no version specified"},
+		{"tools:\n  node:\n    args: [x]\nflows: {}", nil, nil, "no path specified"},
+		{"tools:\n  go: [\"1.22\"]\nflows: {}", nil, nil, "expected a version or a tool definition"},
+	}
+
+	for _, tc := range tests {
+		var def ilofile.Definition
+		err := parseProjectDefinitionYaml([]byte(tc.data), &def)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got: %v, want: error containing %q for %q", err, tc.err, tc.data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("got: %v, want: nil for %q", err, tc.data)
+		}
+		if !reflect.DeepEqual(def.Tools, tc.pins) {
+			t.Fatalf("got: %v, want: %v for %q", def.Tools, tc.pins, tc.data)
+		}
+		for _, name := range tc.toolbox {
+			if _, exists := def.Toolbox[name]; !exists {
+				t.Fatalf("got: %v, want: %s declared by the project", def.Toolbox, name)
+			}
+		}
+	}
+}
+
+func TestParseNeeds(t *testing.T) {
+	var tests = []struct {
+		data     string
+		plan     string
+		expected []string
+		err      string
+	}{
+		{
+			data: `
+flows:
+  build: [{run: make}]
+  test: {needs: [build], steps: [{run: make test}]}
+  release: {needs: [test, build], steps: [{run: make release}]}`,
+			plan:     "release",
+			expected: []string{"build", "test", "release"},
+		},
+		{
+			data: `
+flows:
+  a: {needs: [b], steps: []}
+  b: {needs: [a], steps: []}`,
+			err: "a -> b -> a",
+		},
+		{
+			data: `
+flows:
+  a: {needs: [missing], steps: []}`,
+			err: "flow 'a' needs flow 'missing', which doesn't exist",
+		},
+	}
+
+	for _, tc := range tests {
+		var def ilofile.Definition
+		err := parseProjectDefinitionYaml([]byte(tc.data), &def)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got: %v, want: error containing %s", err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("got: %v, want: nil", err)
+		}
+
+		plan, err := def.Plan(tc.plan)
+		if err != nil {
+			t.Fatalf("got: %v, want: nil", err)
+		}
+		var names []string
+		for _, flow := range plan {
+			names = append(names, flow.Name)
+		}
+		if !reflect.DeepEqual(names, tc.expected) {
+			t.Fatalf("got: %v, want: %v", names, tc.expected)
+		}
+	}
+}
+
+func TestParseSchedules(t *testing.T) {
+	var tests = []struct {
+		data     string
+		expected []string
+		err      string
+	}{
+		{
+			data: `
+flows:
+  nightly: {schedule: "0 3 * * *", steps: [{run: make}]}`,
+			expected: []string{"0 3 * * * Local"},
+		},
+		{
+			data: `
+flows:
+  nightly:
+    schedule:
+      - "@hourly"
+      - {cron: "30 9 * * mon-fri", timezone: Europe/London}
+    steps: [{run: make}]`,
+			expected: []string{"@hourly Local", "30 9 * * mon-fri Europe/London"},
+		},
+		{
+			data: `
+flows:
+  build: [{run: make}]`,
+		},
+		{
+			data: `
+flows:
+  nightly: {schedule: "0 25 * * *", steps: []}`,
+			err: "parse 'nightly' schedule: parse schedule '0 25 * * *': hour field '25'",
+		},
+		{
+			data: `
+flows:
+  nightly: {schedule: {timezone: UTC}, steps: []}`,
+			err: "no cron expression specified",
+		},
+		{
+			data: `
+flows:
+  nightly: {schedule: {cron: "@daily", overlap: sometimes}, steps: []}`,
+			err: "unknown overlap policy 'sometimes'",
+		},
+		{
+			data: `
+flows:
+  nightly:
+    schedule:
+      - {id: release, cron: "0 3 * * *"}
+      - {id: backup, cron: "0 3 * * *"}
+    steps: []`,
+			expected: []string{"release: 0 3 * * * Local", "backup: 0 3 * * * Local"},
+		},
+		{
+			data: `
+flows:
+  nightly: {schedule: ["@daily", "@daily"], steps: []}`,
+			err: "schedule '@daily' is declared twice",
+		},
+		{
+			data: `
+flows:
+  nightly: {schedule: [{id: a, cron: "@daily"}, {id: a, cron: "@hourly"}], steps: []}`,
+			err: "schedule id 'a' is used twice",
+		},
+	}
+
+	for _, tc := range tests {
+		var def ilofile.Definition
+		err := parseProjectDefinitionYaml([]byte(tc.data), &def)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("got: %v, want: error containing %s", err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("got: %v, want: nil", err)
+		}
+
+		var schedules []string
+		for _, flow := range def.Flows {
+			for _, schedule := range flow.Schedules {
+				text := schedule.String() + " " + schedule.Location().String()
+				if schedule.ID != "" {
+					text = schedule.ID + ": " + text
+				}
+				schedules = append(schedules, text)
+			}
+		}
+		if !reflect.DeepEqual(schedules, tc.expected) {
+			t.Fatalf("got: %v, want: %v", schedules, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package iloyml
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a project definition to be written out, such as one generated
+// by ilo init. Unlike ilofile.Definition, it keeps the order of flows and
+// can hold comments.
+type Document struct {
+	// Comment is written at the top of the file.
+	Comment string
+	Name    string
+	Flows   []FlowDocument
+}
+
+type FlowDocument struct {
+	Name        string
+	Description string
+	Needs       []string
+	// Comment is written above the flow.
+	Comment string
+	Steps   []StepDocument
+}
+
+// StepDocument is a step of a flow. A step with only a comment is written
+// as a comment, e.g. for something which couldn't be translated to a step.
+type StepDocument struct {
+	Echo string
+	Run  string
+	// Comment is written above the step.
+	Comment string
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+}
+
+func (d Document) node() *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	if d.Name != "" {
+		root.Content = append(root.Content, scalar("name"), scalar(d.Name))
+	}
+
+	flows := &yaml.Node{Kind: yaml.MappingNode}
+	for _, flow := range d.Flows {
+		value, comments := flow.node()
+		key := scalar(flow.Name)
+		if flow.Comment != "" {
+			comments = append([]string{flow.Comment}, comments...)
+		}
+		key.HeadComment = strings.Join(comments, "\n")
+		flows.Content = append(flows.Content, key, value)
+	}
+	root.Content = append(root.Content, scalar("flows"), flows)
+
+	return &yaml.Node{Kind: yaml.DocumentNode, HeadComment: d.Comment, Content: []*yaml.Node{root}}
+}
+
+// node writes a flow as a list of steps, or as a mapping if it has a
+// description or needs other flows. Comments which can't be placed among
+// the steps, because there are none, are returned to go above the flow.
+func (f FlowDocument) node() (*yaml.Node, []string) {
+	steps := &yaml.Node{Kind: yaml.SequenceNode}
+	var comments []string
+
+	for _, step := range f.Steps {
+		if step.Comment != "" {
+			comments = append(comments, step.Comment)
+		}
+		if step.Run == "" && step.Echo == "" {
+			continue
+		}
+
+		stepNode := &yaml.Node{Kind: yaml.MappingNode, HeadComment: strings.Join(comments, "\n")}
+		if step.Run != "" {
+			stepNode.Content = append(stepNode.Content, scalar("run"), scalar(step.Run))
+		} else {
+			stepNode.Content = append(stepNode.Content, scalar("echo"), scalar(step.Echo))
+		}
+		steps.Content = append(steps.Content, stepNode)
+		comments = nil
+	}
+
+	if len(steps.Content) == 0 {
+		steps.Style = yaml.FlowStyle
+	} else if len(comments) > 0 {
+		// Comments after the last step follow its value
+		last := steps.Content[len(steps.Content)-1]
+		last.Content[1].FootComment = strings.Join(comments, "\n")
+		comments = nil
+	}
+
+	if f.Description == "" && len(f.Needs) == 0 {
+		return steps, comments
+	}
+
+	flow := &yaml.Node{Kind: yaml.MappingNode}
+	if f.Description != "" {
+		flow.Content = append(flow.Content, scalar("description"), scalar(f.Description))
+	}
+	if len(f.Needs) > 0 {
+		needs := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, need := range f.Needs {
+			needs.Content = append(needs.Content, scalar(need))
+		}
+		flow.Content = append(flow.Content, scalar("needs"), needs)
+	}
+	flow.Content = append(flow.Content, scalar("steps"), steps)
+	return flow, comments
+}
+
+// Write writes a project definition as YAML.
+func Write(w io.Writer, doc Document) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc.node()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// Create writes a project definition to a new file at path. An existing
+// file is only replaced if overwrite is set.
+func Create(path string, doc Document, overwrite bool) error {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("create '%s': file already exists", path)
+	} else if err != nil {
+		return fmt.Errorf("create '%s': %w", path, err)
+	}
+
+	if err := Write(file, doc); err != nil {
+		file.Close()
+		return fmt.Errorf("write '%s': %w", path, err)
+	}
+	return file.Close()
+}
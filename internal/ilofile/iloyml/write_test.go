@@ -0,0 +1,49 @@
+package iloyml
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+func TestWriteDocument(t *testing.T) {
+	var doc = Document{
+		Comment: "Generated by ilo init",
+		Name:    "demo",
+		Flows: []FlowDocument{
+			{Name: "test", Steps: []StepDocument{{Run: "$go test ./..."}}},
+			{Name: "build", Description: "Build it", Comment: "from go.mod", Steps: []StepDocument{
+				{Echo: "Building"},
+				{Run: `$go build -ldflags "-s -w" ./...`, Comment: "strip symbols"},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// Flows keep their order, which a map can't
+	var out = buf.String()
+	if strings.Index(out, "test:") > strings.Index(out, "build:") {
+		t.Fatalf("got: %s, want: test before build", out)
+	}
+	for _, expected := range []string{"# Generated by ilo init\n", "# from go.mod\n", "# strip symbols\n"} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("got: %s, want: output containing %q", out, expected)
+		}
+	}
+
+	var def ilofile.Definition
+	if err := parseProjectDefinitionYaml(buf.Bytes(), &def); err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+	var build = def.Flows["build"]
+	if def.Name != "demo" || build.Description != "Build it" || len(build.Steps) != 2 ||
+		build.Steps[1].String() != `$go build -ldflags "-s -w" ./...` {
+		t.Fatalf("got: %+v, want: the written document", def)
+	}
+}
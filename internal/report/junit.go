@@ -0,0 +1,104 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func writeJUnit(writer io.Writer, suites []testSuite) error {
+	report := junitTestSuites{}
+	var total time.Duration
+
+	for _, suite := range suites {
+		junitSuite := junitTestSuite{
+			Name:      suite.name,
+			Tests:     len(suite.cases),
+			Time:      seconds(suite.duration),
+			Timestamp: suite.start.Format("2006-01-02T15:04:05"),
+		}
+
+		for _, testCase := range suite.cases {
+			junitCase := junitTestCase{
+				Name:      testCase.name,
+				ClassName: suite.name,
+				Time:      seconds(testCase.duration),
+				SystemOut: strings.Join(testCase.output, "\n"),
+			}
+
+			switch {
+			case testCase.failed:
+				junitSuite.Failures += 1
+				junitCase.Failure = &junitFailure{
+					Message: testCase.failure,
+					Text:    testCase.failure,
+				}
+			case testCase.skipped:
+				junitSuite.Skipped += 1
+				junitCase.Skipped = &junitSkipped{Message: testCase.skipReason}
+			}
+
+			junitSuite.TestCases = append(junitSuite.TestCases, junitCase)
+		}
+
+		report.Tests += junitSuite.Tests
+		report.Failures += junitSuite.Failures
+		total += suite.duration
+		report.Suites = append(report.Suites, junitSuite)
+	}
+	report.Time = seconds(total)
+
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(writer, "\n")
+	return err
+}
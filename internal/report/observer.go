@@ -0,0 +1,191 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// Format is a file format reports can be written in.
+type Format string
+
+const (
+	JUnit Format = "junit"
+	TAP   Format = "tap"
+	// Trace is the Chrome trace event format, for viewing where a run
+	// spends its time in chrome://tracing or Perfetto.
+	Trace Format = "trace"
+)
+
+type testCase struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+	output   []string
+	failure  string
+	failed   bool
+	skipped  bool
+	// skipReason is why a skipped step didn't run, if not because an
+	// earlier step failed.
+	skipReason string
+}
+
+type testSuite struct {
+	name     string
+	start    time.Time
+	duration time.Duration
+	cases    []testCase
+}
+
+// Observer records the result of every flow and step it observes, and
+// writes them as a test report when closed. Flows map to test suites and
+// steps to test cases.
+type Observer struct {
+	mu      sync.Mutex
+	format  Format
+	writer  io.Writer
+	project *ilofile.Definition
+	suites  []testSuite
+	// running maps flows that have been entered but not finished to the
+	// index of their suite.
+	running map[flowKey]int
+}
+
+type flowKey struct {
+	runID string
+	flow  *ilofile.Flow
+}
+
+func init() {
+	for _, format := range []Format{JUnit, TAP, Trace} {
+		exec.RegisterObserver(string(format), func(opts exec.ObserverOptions) (exec.ExecutionObserver, error) {
+			return NewObserver(opts.Project, format, opts.Output)
+		})
+	}
+}
+
+// NewObserver creates an observer which writes a report in the given format
+// to writer when it is closed.
+func NewObserver(project *ilofile.Definition, format Format, writer io.Writer) (*Observer, error) {
+	if format != JUnit && format != TAP && format != Trace {
+		return nil, fmt.Errorf("unknown report format '%s'", format)
+	}
+	return &Observer{
+		project: project,
+		format:  format,
+		writer:  writer,
+		running: make(map[flowKey]int),
+	}, nil
+}
+
+func (o *Observer) suite(e exec.Event) *testSuite {
+	return &o.suites[o.running[flowKey{e.RunID, e.Flow}]]
+}
+
+func caseName(e exec.Event) string {
+	return fmt.Sprintf("%d: %s", e.StepIndex+1, e.Step.String())
+}
+
+func (o *Observer) FlowEntered(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	name := e.Flow.Name
+	if o.project != nil && o.project.Name != "" {
+		name = fmt.Sprintf("%s / %s", o.project.Name, e.Flow.Name)
+	}
+
+	o.running[flowKey{e.RunID, e.Flow}] = len(o.suites)
+	o.suites = append(o.suites, testSuite{name: name, start: e.Time})
+}
+
+func (o *Observer) StepEntered(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	suite := o.suite(e)
+	suite.cases = append(suite.cases, testCase{name: caseName(e), start: e.Time})
+}
+
+func (o *Observer) StepOutput(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	testCase := &o.suite(e).cases[e.StepIndex]
+	testCase.output = append(testCase.output, e.Text)
+}
+
+func (o *Observer) StepPassed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.suite(e).cases[e.StepIndex].duration = e.Duration
+}
+
+func (o *Observer) StepFailed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	testCase := &o.suite(e).cases[e.StepIndex]
+	testCase.duration = e.Duration
+	testCase.failed = true
+	testCase.failure = e.Err.Error()
+}
+
+func (o *Observer) FlowPassed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.suite(e).duration = e.Duration
+	delete(o.running, flowKey{e.RunID, e.Flow})
+}
+
+func (o *Observer) FlowFailed(e exec.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	suite := o.suite(e)
+	suite.duration = e.Duration
+
+	// Steps after a failure never run, so report them as skipped, as are
+	// all the steps of a skipped flow
+	skipReason := ""
+	if e.Skipped {
+		skipReason = e.Err.Error()
+	}
+	for i := len(suite.cases); i < len(e.Flow.Steps); i++ {
+		suite.cases = append(suite.cases, testCase{
+			name:       caseName(exec.Event{StepIndex: i, Step: e.Flow.Steps[i]}),
+			skipped:    true,
+			skipReason: skipReason,
+		})
+	}
+	delete(o.running, flowKey{e.RunID, e.Flow})
+}
+
+// Write writes the report of everything observed so far to writer.
+func (o *Observer) Write(writer io.Writer) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	switch o.format {
+	case JUnit:
+		return writeJUnit(writer, o.suites)
+	case Trace:
+		return writeTrace(writer, o.suites)
+	default:
+		return writeTAP(writer, o.suites)
+	}
+}
+
+// Close writes the report to the observer's writer.
+func (o *Observer) Close() error {
+	if err := o.Write(o.writer); err != nil {
+		return fmt.Errorf("write %s report: %w", o.format, err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+type fakeStep string
+
+func (s fakeStep) StepType() ilofile.StepType { return ilofile.StepEchoMessage }
+func (s fakeStep) String() string             { return string(s) }
+
+func observeFailingFlow(t *testing.T, format Format) string {
+	var project = &ilofile.Definition{Name: "proj"}
+	var flow = ilofile.Flow{Name: "build", Steps: []ilofile.Step{fakeStep("a"), fakeStep("b"), fakeStep("c")}}
+
+	observer, err := NewObserver(project, format, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec.RunFlow("run", flow, func(step ilofile.Step, params exec.ExecParams) error {
+		if step == fakeStep("b") {
+			return errors.New("exit status 2")
+		}
+		params.Output(exec.Stdout, "hello")
+		return nil
+	}, nil, observer)
+
+	var buf bytes.Buffer
+	if err := observer.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out = observeFailingFlow(t, JUnit)
+
+	for _, expected := range []string{
+		`<testsuite name="proj / build" tests="3" failures="1" skipped="1"`,
+		`<system-out>hello</system-out>`,
+		`<failure message="exit status 2">`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("got: %s, want: output containing %s", out, expected)
+		}
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	var out = observeFailingFlow(t, TAP)
+
+	for _, expected := range []string{
+		"1..3\n",
+		"ok 1 - proj / build / 1: a\n",
+		"not ok 2 - proj / build / 2: b\n",
+		"ok 3 - proj / build / 3: c # SKIP not reached\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("got: %s, want: output containing %q", out, expected)
+		}
+	}
+}
+
+func TestWriteTrace(t *testing.T) {
+	var out = observeFailingFlow(t, Trace)
+
+	for _, expected := range []string{
+		`"name": "proj / build"`,
+		`"name": "2: b"`,
+		`"error": "exit status 2"`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("got: %s, want: output containing %s", out, expected)
+		}
+	}
+	if strings.Contains(out, `"3: c"`) {
+		t.Fatalf("got: %s, want: no span for skipped step", out)
+	}
+}
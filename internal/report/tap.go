@@ -0,0 +1,57 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func writeTAP(writer io.Writer, suites []testSuite) error {
+	var sb strings.Builder
+
+	total := 0
+	for _, suite := range suites {
+		total += len(suite.cases)
+	}
+
+	sb.WriteString("TAP version 13\n")
+	fmt.Fprintf(&sb, "1..%d\n", total)
+
+	number := 0
+	for _, suite := range suites {
+		for _, testCase := range suite.cases {
+			number += 1
+			description := strings.ReplaceAll(fmt.Sprintf("%s / %s", suite.name, testCase.name), "#", `\#`)
+
+			switch {
+			case testCase.skipped:
+				reason := "not reached"
+				if testCase.skipReason != "" {
+					reason = testCase.skipReason
+				}
+				fmt.Fprintf(&sb, "ok %d - %s # SKIP %s\n", number, description, reason)
+				continue
+			case testCase.failed:
+				fmt.Fprintf(&sb, "not ok %d - %s\n", number, description)
+			default:
+				fmt.Fprintf(&sb, "ok %d - %s\n", number, description)
+			}
+
+			sb.WriteString("  ---\n")
+			fmt.Fprintf(&sb, "  duration_ms: %d\n", testCase.duration.Milliseconds())
+			if testCase.failed {
+				fmt.Fprintf(&sb, "  message: %q\n", testCase.failure)
+			}
+			if len(testCase.output) > 0 {
+				sb.WriteString("  output: |\n")
+				for _, line := range testCase.output {
+					fmt.Fprintf(&sb, "    %s\n", line)
+				}
+			}
+			sb.WriteString("  ...\n")
+		}
+	}
+
+	_, err := io.WriteString(writer, sb.String())
+	return err
+}
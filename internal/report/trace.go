@@ -0,0 +1,74 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// traceEvent is a complete event in the Chrome trace event format, with
+// times in microseconds.
+type traceEvent struct {
+	Name     string         `json:"name"`
+	Category string         `json:"cat"`
+	Phase    string         `json:"ph"`
+	Time     int64          `json:"ts"`
+	Duration int64          `json:"dur"`
+	Process  int            `json:"pid"`
+	Thread   int            `json:"tid"`
+	Args     map[string]any `json:"args,omitempty"`
+}
+
+type traceFile struct {
+	TraceEvents     []traceEvent `json:"traceEvents"`
+	DisplayTimeUnit string       `json:"displayTimeUnit"`
+}
+
+// writeTrace writes each flow and step as a span, with each flow on its own
+// thread so that flows running at the same time don't overlap.
+func writeTrace(writer io.Writer, suites []testSuite) error {
+	trace := traceFile{TraceEvents: []traceEvent{}, DisplayTimeUnit: "ms"}
+
+	var origin time.Time
+	for _, suite := range suites {
+		if origin.IsZero() || suite.start.Before(origin) {
+			origin = suite.start
+		}
+	}
+
+	for i, suite := range suites {
+		trace.TraceEvents = append(trace.TraceEvents, traceEvent{
+			Name:     suite.name,
+			Category: "flow",
+			Phase:    "X",
+			Time:     suite.start.Sub(origin).Microseconds(),
+			Duration: suite.duration.Microseconds(),
+			Process:  1,
+			Thread:   i + 1,
+		})
+
+		for _, testCase := range suite.cases {
+			if testCase.skipped {
+				continue
+			}
+
+			event := traceEvent{
+				Name:     testCase.name,
+				Category: "step",
+				Phase:    "X",
+				Time:     testCase.start.Sub(origin).Microseconds(),
+				Duration: testCase.duration.Microseconds(),
+				Process:  1,
+				Thread:   i + 1,
+			}
+			if testCase.failed {
+				event.Args = map[string]any{"error": testCase.failure}
+			}
+			trace.TraceEvents = append(trace.TraceEvents, event)
+		}
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(trace)
+}
@@ -0,0 +1,166 @@
+package runlog
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/exec"
+)
+
+const (
+	DefaultMaxAge  = "30d"
+	DefaultMaxSize = "1GB"
+)
+
+// Retention limits which run logs are kept, and is saved as logs.yml in the
+// user's ilo config directory. Empty settings use the defaults, and "0"
+// removes a limit.
+type Retention struct {
+	// MaxAge removes runs that started longer ago than this, e.g. 14d or 72h.
+	MaxAge string `yaml:"max_age,omitempty"`
+	// MaxSize removes the oldest runs while the logs of all runs together
+	// take up more than this, e.g. 500MB.
+	MaxSize string `yaml:"max_size,omitempty"`
+}
+
+// LoadRetention loads the retention settings, filling in defaults.
+func LoadRetention(loader provide.Loader[Retention]) (Retention, error) {
+	retention, err := loader.Load("logs", provide.YamlUnmarshal[Retention])
+	if err != nil {
+		return Retention{}, fmt.Errorf("load log retention: %w", err)
+	}
+
+	if retention.MaxAge == "" {
+		retention.MaxAge = DefaultMaxAge
+	}
+	if retention.MaxSize == "" {
+		retention.MaxSize = DefaultMaxSize
+	}
+	return *retention, nil
+}
+
+// ParseAge parses an age such as 30d or 12h. Days are supported on top of
+// the units understood by time.ParseDuration.
+func ParseAge(text string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(text, "d"); found {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age '%s'", text)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+
+	age, err := time.ParseDuration(text)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age '%s'", text)
+	}
+	return age, nil
+}
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"K", 1 << 10},
+	{"M", 1 << 20},
+	{"G", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as 500MB or 2G, where units are powers of
+// 1024 and a bare number is in bytes.
+func ParseSize(text string) (int64, error) {
+	number, scale := strings.TrimSpace(strings.ToUpper(text)), 1.0
+	for _, unit := range sizeUnits {
+		if trimmed, found := strings.CutSuffix(number, unit.suffix); found {
+			number, scale = strings.TrimSpace(trimmed), unit.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", text)
+	}
+	return int64(n * scale), nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// Prune removes the logs of runs which are too old, then of the oldest runs
+// until the logs fit in the maximum size, and returns the IDs of the runs
+// removed. The newest run, and runs which are still running and not too
+// old, are always kept.
+func (s Store) Prune(retention Retention, now time.Time) ([]string, error) {
+	maxAge, err := ParseAge(retention.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := ParseSize(retention.MaxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := s.IDs()
+	if err != nil {
+		return nil, err
+	}
+
+	type storedRun struct {
+		id      string
+		start   time.Time
+		running bool
+		size    int64
+	}
+
+	runs := make([]storedRun, len(ids))
+	var total int64
+	for i, id := range ids {
+		runs[i] = storedRun{id: id, size: dirSize(s.RunDir(id))}
+		if run, err := s.Load(id); err == nil {
+			runs[i].start = run.Start
+			runs[i].running = run.Status == exec.StatusRunning
+		} else if info, err := os.Stat(s.RunDir(id)); err == nil {
+			runs[i].start = info.ModTime()
+		}
+		total += runs[i].size
+	}
+
+	var removed []string
+	// Runs are newest first, so walk backwards to remove the oldest first
+	for i := len(runs) - 1; i > 0; i-- {
+		run := runs[i]
+		tooOld := maxAge > 0 && now.Sub(run.start) > maxAge
+		tooBig := maxSize > 0 && total > maxSize && !run.running
+		if !tooOld && !tooBig {
+			continue
+		}
+
+		if err := os.RemoveAll(s.RunDir(run.id)); err != nil {
+			return removed, fmt.Errorf("remove run '%s': %w", run.id, err)
+		}
+		removed = append(removed, run.id)
+		total -= run.size
+	}
+	return removed, nil
+}
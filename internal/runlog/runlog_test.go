@@ -0,0 +1,85 @@
+package runlog
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+type fakeStep string
+
+func (s fakeStep) StepType() ilofile.StepType { return ilofile.StepEchoMessage }
+func (s fakeStep) String() string             { return string(s) }
+
+func TestWriteRunLog(t *testing.T) {
+	var store = Store{Dir: t.TempDir()}
+	var flow = ilofile.Flow{Name: "build", Steps: []ilofile.Step{fakeStep("a"), fakeStep("b"), fakeStep("c")}}
+
+	writer, err := store.NewWriter("20240101T000000-abcdef", &ilofile.Definition{Name: "proj"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec.RunFlow("20240101T000000-abcdef", flow, func(step ilofile.Step, params exec.ExecParams) error {
+		params.Output(exec.Stdout, "running "+step.String())
+		if step == fakeStep("b") {
+			return errors.New("exit status 2")
+		}
+		return nil
+	}, nil, writer)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := store.Find("last")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != exec.StatusFailed || len(run.Flows) != 1 {
+		t.Fatalf("got: %s with %d flows, want: failed with 1 flow", run.Status, len(run.Flows))
+	}
+
+	var statuses []exec.Status
+	for _, step := range run.Flows[0].Steps {
+		statuses = append(statuses, step.Status)
+	}
+	var expected = []exec.Status{exec.StatusPassed, exec.StatusFailed, exec.StatusSkipped}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("got: %v, want: %v", statuses, expected)
+	}
+
+	output, err := os.ReadFile(store.LogPath(run, run.Flows[0].Steps[1]))
+	if err != nil || string(output) != "running b\n" {
+		t.Fatalf("got: %q, %v, want: %q, nil", output, err, "running b\n")
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	var sizes = []struct {
+		text     string
+		expected int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"2KB", 2048},
+		{"1.5 MB", 3 << 19},
+		{"1g", 1 << 30},
+	}
+	for _, tc := range sizes {
+		if got, err := ParseSize(tc.text); err != nil || got != tc.expected {
+			t.Fatalf("ParseSize(%s) got: %d, %v, want: %d, nil", tc.text, got, err, tc.expected)
+		}
+	}
+
+	if got, err := ParseAge("14d"); err != nil || got != 14*24*time.Hour {
+		t.Fatalf("got: %v, %v, want: 336h, nil", got, err)
+	}
+	if _, err := ParseAge("soon"); err == nil {
+		t.Fatalf("got: nil, want: error for invalid age")
+	}
+}
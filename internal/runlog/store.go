@@ -0,0 +1,183 @@
+package runlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+)
+
+// metadataFile is the name of the file holding a run's metadata.
+const metadataFile = "run.json"
+
+// Store is a directory of run logs, laid out as <dir>/<run-id>/ with a
+// run.json describing the run and a log file for each step that ran.
+type Store struct {
+	Dir string
+}
+
+// Run describes a run, as written to its run.json.
+type Run struct {
+	ID          string      `json:"id"`
+	Project     string      `json:"project,omitempty"`
+	ProjectName string      `json:"project_name,omitempty"`
+	Status      exec.Status `json:"status"`
+	Start       time.Time   `json:"start"`
+	End         time.Time   `json:"end"`
+	Flows       []Flow      `json:"flows"`
+}
+
+// Flow describes a flow in a run.
+type Flow struct {
+	Name   string      `json:"name"`
+	Status exec.Status `json:"status"`
+	Start  time.Time   `json:"start"`
+	End    time.Time   `json:"end"`
+	Steps  []Step      `json:"steps"`
+	// Error is why a skipped flow didn't run.
+	Error string `json:"error,omitempty"`
+}
+
+// Step describes a step in a flow. Log is the path of the step's output,
+// relative to the run directory, and is empty for steps that never ran.
+type Step struct {
+	Index    int         `json:"index"`
+	Text     string      `json:"text"`
+	Status   exec.Status `json:"status"`
+	Start    time.Time   `json:"start"`
+	End      time.Time   `json:"end"`
+	ExitCode int         `json:"exit_code"`
+	Error    string      `json:"error,omitempty"`
+	Log      string      `json:"log,omitempty"`
+}
+
+func (r Run) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// NewCacheStore returns the run log store in the user's cache directory.
+func NewCacheStore() Store {
+	cachePath, err := os.UserCacheDir()
+	if err != nil {
+		panic(err)
+	}
+	return Store{Dir: filepath.Join(cachePath, "ilo", "logs")}
+}
+
+// RunDir returns the directory holding the logs of a run.
+func (s Store) RunDir(runID string) string {
+	return filepath.Join(s.Dir, runID)
+}
+
+// Load reads the metadata of a run.
+func (s Store) Load(runID string) (Run, error) {
+	var run Run
+
+	data, err := os.ReadFile(filepath.Join(s.RunDir(runID), metadataFile))
+	if err != nil {
+		return run, fmt.Errorf("load run '%s': %w", runID, err)
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return run, fmt.Errorf("load run '%s': %w", runID, err)
+	}
+	return run, nil
+}
+
+func (s Store) save(run Run) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write the metadata in one go so that readers never see half of it
+	path := filepath.Join(s.RunDir(run.ID), metadataFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// IDs returns the ID of every stored run, newest first.
+func (s Store) IDs() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			ids = append(ids, entry.Name())
+		}
+	}
+
+	// Run IDs start with the time the run started
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	return ids, nil
+}
+
+// List returns the metadata of every stored run, newest first. Runs with
+// missing or unreadable metadata are left out.
+func (s Store) List() ([]Run, error) {
+	ids, err := s.IDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []Run
+	for _, id := range ids {
+		if run, err := s.Load(id); err == nil {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+// Find returns the run with the given ID, or the only run whose ID starts
+// with it. The reference "last" finds the newest run.
+func (s Store) Find(ref string) (Run, error) {
+	ids, err := s.IDs()
+	if err != nil {
+		return Run{}, err
+	}
+
+	if ref == "last" {
+		if len(ids) == 0 {
+			return Run{}, errors.New("no runs have been logged")
+		}
+		return s.Load(ids[0])
+	}
+
+	var matches []string
+	for _, id := range ids {
+		if id == ref {
+			return s.Load(id)
+		}
+		if strings.HasPrefix(id, ref) {
+			matches = append(matches, id)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Run{}, fmt.Errorf("no run '%s' has been logged", ref)
+	case 1:
+		return s.Load(matches[0])
+	default:
+		return Run{}, fmt.Errorf("'%s' matches %d runs, use more of the run ID", ref, len(matches))
+	}
+}
+
+// LogPath returns the full path of a step's log.
+func (s Store) LogPath(run Run, step Step) string {
+	return filepath.Join(s.RunDir(run.ID), step.Log)
+}
@@ -0,0 +1,234 @@
+package runlog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// Writer is an observer which writes the output of every step in a run to
+// its own log file, and keeps the run's metadata up to date.
+type Writer struct {
+	mu       sync.Mutex
+	store    Store
+	run      Run
+	recorder *exec.Recorder
+
+	// flows holds the log of each flow in the order they were entered,
+	// matching the order of the recorder's flows.
+	flows  []*flowLog
+	byFlow map[*ilofile.Flow]*flowLog
+}
+
+// flowLog is the log directory of a flow and the log of its current step.
+type flowLog struct {
+	dir  string
+	file *os.File
+	buf  *bufio.Writer
+}
+
+func (l *flowLog) closeStep() error {
+	if l.file == nil {
+		return nil
+	}
+
+	err := errors.Join(l.buf.Flush(), l.file.Close())
+	l.file = nil
+	l.buf = nil
+	return err
+}
+
+// NewWriter creates the log directory for a run and returns an observer
+// which writes the run's logs into it.
+func (s Store) NewWriter(runID string, project *ilofile.Definition) (*Writer, error) {
+	if err := os.MkdirAll(s.RunDir(runID), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create run log: %w", err)
+	}
+
+	w := &Writer{
+		store:    s,
+		run:      Run{ID: runID, Status: exec.StatusRunning, Start: time.Now()},
+		recorder: exec.NewRecorder(),
+		byFlow:   make(map[*ilofile.Flow]*flowLog),
+	}
+	if project != nil {
+		w.run.Project = project.Path
+		w.run.ProjectName = project.Name
+	}
+
+	if err := w.save(); err != nil {
+		return nil, fmt.Errorf("create run log: %w", err)
+	}
+	return w, nil
+}
+
+// Dir returns the directory the run is logged to.
+func (w *Writer) Dir() string {
+	return w.store.RunDir(w.run.ID)
+}
+
+// flowDirName returns a directory name for a flow which is safe to use in
+// a path and not yet used by another flow in the run.
+func (w *Writer) flowDirName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	used := make(map[string]bool)
+	for _, flow := range w.flows {
+		used[flow.dir] = true
+	}
+
+	dir := name
+	for i := 2; used[dir]; i++ {
+		dir = fmt.Sprintf("%s-%d", name, i)
+	}
+	return dir
+}
+
+func stepLogName(index int) string {
+	return fmt.Sprintf("%d.log", index+1)
+}
+
+// save writes the run's metadata as recorded so far.
+func (w *Writer) save() error {
+	run := w.run
+	run.Flows = nil
+
+	for i, record := range w.recorder.Flows() {
+		flow := Flow{
+			Name:   record.Name,
+			Status: record.Status,
+			Start:  record.Start,
+			End:    record.End,
+			Error:  record.Error,
+		}
+
+		for _, step := range record.Steps {
+			logged := Step{
+				Index:    step.Index,
+				Text:     step.Text,
+				Status:   step.Status,
+				Start:    step.Start,
+				End:      step.End,
+				ExitCode: step.ExitCode,
+				Error:    step.Error,
+			}
+			if step.Status != exec.StatusSkipped {
+				logged.Log = filepath.Join(w.flows[i].dir, stepLogName(step.Index))
+			}
+			flow.Steps = append(flow.Steps, logged)
+		}
+		run.Flows = append(run.Flows, flow)
+	}
+
+	return w.store.save(run)
+}
+
+func (w *Writer) FlowEntered(e exec.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.recorder.FlowEntered(e)
+	flow := &flowLog{dir: w.flowDirName(e.Flow.Name)}
+	w.flows = append(w.flows, flow)
+	w.byFlow[e.Flow] = flow
+
+	// Logging is best-effort, a full disk shouldn't stop the flow
+	_ = os.MkdirAll(filepath.Join(w.Dir(), flow.dir), os.ModePerm)
+	_ = w.save()
+}
+
+func (w *Writer) StepEntered(e exec.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.recorder.StepEntered(e)
+	flow := w.byFlow[e.Flow]
+	_ = flow.closeStep()
+
+	path := filepath.Join(w.Dir(), flow.dir, stepLogName(e.StepIndex))
+	if file, err := os.Create(path); err == nil {
+		flow.file = file
+		flow.buf = bufio.NewWriter(file)
+	}
+}
+
+func (w *Writer) StepOutput(e exec.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if flow := w.byFlow[e.Flow]; flow.buf != nil {
+		flow.buf.WriteString(e.Text)
+		flow.buf.WriteByte('\n')
+	}
+}
+
+func (w *Writer) StepPassed(e exec.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.recorder.StepPassed(e)
+	_ = w.byFlow[e.Flow].closeStep()
+}
+
+func (w *Writer) StepFailed(e exec.Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.recorder.StepFailed(e)
+	_ = w.byFlow[e.Flow].closeStep()
+}
+
+func (w *Writer) FlowPassed(e exec.Event) {
+	w.finishFlow(e, w.recorder.FlowPassed)
+}
+
+func (w *Writer) FlowFailed(e exec.Event) {
+	w.finishFlow(e, w.recorder.FlowFailed)
+}
+
+func (w *Writer) finishFlow(e exec.Event, record func(exec.Event)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	record(e)
+	_ = w.byFlow[e.Flow].closeStep()
+	delete(w.byFlow, e.Flow)
+	_ = w.save()
+}
+
+// Close finishes the run, recording whether every flow passed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	for _, flow := range w.flows {
+		errs = append(errs, flow.closeStep())
+	}
+
+	w.run.End = time.Now()
+	w.run.Status = exec.StatusPassed
+	for _, flow := range w.recorder.Flows() {
+		if flow.Status != exec.StatusPassed {
+			w.run.Status = exec.StatusFailed
+		}
+	}
+
+	if err := errors.Join(append(errs, w.save())...); err != nil {
+		return fmt.Errorf("write run log: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+// Package scaffold generates project definitions from what can be found in
+// a project directory.
+package scaffold
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+)
+
+// Project is a starter project definition detected from a directory.
+type Project struct {
+	Document iloyml.Document
+	// Sources lists the files the flows were generated from, e.g. go.mod.
+	Sources []string
+	// Tools lists every tool referenced by the flows.
+	Tools []string
+}
+
+// flowOrder is the order in which the usual flows are written.
+var flowOrder = []string{"build", "test", "lint"}
+
+// detector recognises a kind of project from a file in its directory,
+// returning the steps to run for each flow.
+type detector struct {
+	file   string
+	detect func(dir string, path string) (map[string][]string, error)
+	// wraps is set for task runners whose targets usually wrap the other
+	// build systems, so their flows replace those of other detectors.
+	wraps bool
+}
+
+var detectors = []detector{
+	{"Makefile", detectMake, true},
+	{"go.mod", detectGo, false},
+	{"Cargo.toml", detectCargo, false},
+	{"package.json", detectNode, false},
+	{"pyproject.toml", detectPython, false},
+	{"setup.py", detectPython, false},
+	{"requirements.txt", detectPython, false},
+	{"CMakeLists.txt", detectCMake, false},
+}
+
+// Detect inspects a directory for the files of known build systems and
+// generates build, test and lint flows for each one found.
+func Detect(dir string) (Project, error) {
+	project := Project{Document: iloyml.Document{Name: filepath.Base(dir)}}
+	steps := make(map[string][]string)
+	sources := make(map[string][]string)
+	wrapped := make(map[string]bool)
+
+	for _, d := range detectors {
+		path := filepath.Join(dir, d.file)
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return project, fmt.Errorf("detect '%s': %w", path, err)
+		}
+
+		flows, err := d.detect(dir, path)
+		if err != nil {
+			return project, fmt.Errorf("detect '%s': %w", path, err)
+		}
+		for name, runs := range flows {
+			if wrapped[name] {
+				continue
+			}
+			wrapped[name] = d.wraps
+			project.Sources = appendUnique(project.Sources, d.file)
+
+			for _, run := range runs {
+				// Python is recognised by several files
+				if !slices.Contains(steps[name], run) {
+					steps[name] = append(steps[name], run)
+					sources[name] = appendUnique(sources[name], d.file)
+				}
+			}
+		}
+	}
+
+	for _, name := range flowOrder {
+		if len(steps[name]) == 0 {
+			continue
+		}
+
+		flow := iloyml.FlowDocument{Name: name, Comment: "from " + strings.Join(sources[name], ", ")}
+		for _, run := range steps[name] {
+			flow.Steps = append(flow.Steps, iloyml.StepDocument{Run: run})
+		}
+		project.Document.Flows = append(project.Document.Flows, flow)
+	}
+
+	project.Tools = documentTools(project.Document)
+	return project, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func exists(dir string, name string) bool {
+	_, err := os.Stat(filepath.Join(dir, name))
+	return err == nil
+}
+
+func detectGo(dir string, path string) (map[string][]string, error) {
+	flows := map[string][]string{
+		"build": {"$go build ./..."},
+		"test":  {"$go test ./..."},
+		"lint":  {"$go vet ./..."},
+	}
+	if exists(dir, ".golangci.yml") || exists(dir, ".golangci.yaml") {
+		flows["lint"] = append(flows["lint"], "$golangci-lint run")
+	}
+	return flows, nil
+}
+
+func detectCargo(dir string, path string) (map[string][]string, error) {
+	return map[string][]string{
+		"build": {"$cargo build"},
+		"test":  {"$cargo test"},
+		"lint":  {"$cargo clippy -- -D warnings"},
+	}, nil
+}
+
+// nodeManager returns the package manager a Node project uses, from its
+// lockfile.
+func nodeManager(dir string) string {
+	switch {
+	case exists(dir, "pnpm-lock.yaml"):
+		return "pnpm"
+	case exists(dir, "yarn.lock"):
+		return "yarn"
+	case exists(dir, "bun.lockb"), exists(dir, "bun.lock"):
+		return "bun"
+	default:
+		return "npm"
+	}
+}
+
+// npmDefaultTest is the test script npm init writes, which always fails.
+const npmDefaultTest = `echo "Error: no test specified" && exit 1`
+
+func detectNode(dir string, path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	manager := nodeManager(dir)
+
+	flows := make(map[string][]string)
+	for _, name := range flowOrder {
+		script, ok := manifest.Scripts[name]
+		if !ok || (name == "test" && script == npmDefaultTest) {
+			continue
+		}
+		flows[name] = []string{fmt.Sprintf("$%s run %s", manager, name)}
+	}
+	return flows, nil
+}
+
+func detectPython(dir string, path string) (map[string][]string, error) {
+	var config string
+	for _, name := range []string{"pyproject.toml", "setup.cfg", "tox.ini", "requirements.txt", "requirements-dev.txt"} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+			config += string(data)
+		}
+	}
+
+	flows := map[string][]string{"test": {"$python -m unittest"}}
+	if strings.Contains(config, "pytest") {
+		flows["test"] = []string{"$python -m pytest"}
+	}
+	if filepath.Base(path) == "pyproject.toml" {
+		flows["build"] = []string{"$python -m build"}
+	}
+	if strings.Contains(config, "ruff") {
+		flows["lint"] = []string{"$ruff check ."}
+	} else if strings.Contains(config, "flake8") {
+		flows["lint"] = []string{"$python -m flake8"}
+	}
+	return flows, nil
+}
+
+func detectCMake(dir string, path string) (map[string][]string, error) {
+	return map[string][]string{
+		"build": {"$cmake -S . -B build", "$cmake --build build"},
+		"test":  {"$ctest --test-dir build"},
+	}, nil
+}
+
+var makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_.-]*)\s*:([^=]|$)`)
+
+// makeTargets returns the names of the targets defined in a Makefile.
+func makeTargets(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var targets []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if match := makeTargetPattern.FindStringSubmatch(scanner.Text()); match != nil {
+			targets = appendUnique(targets, match[1])
+		}
+	}
+	return targets, scanner.Err()
+}
+
+func detectMake(dir string, path string) (map[string][]string, error) {
+	targets, err := makeTargets(path)
+	if err != nil {
+		return nil, err
+	}
+
+	flows := make(map[string][]string)
+	for _, name := range flowOrder {
+		if slices.Contains(targets, name) {
+			flows[name] = []string{"$make " + name}
+		}
+	}
+	if _, ok := flows["test"]; !ok && slices.Contains(targets, "check") {
+		flows["test"] = []string{"$make check"}
+	}
+	if _, ok := flows["build"]; !ok && len(targets) > 0 && !slices.Contains([]string{"test", "check", "lint"}, targets[0]) {
+		// The first target is the default, which usually builds the project
+		flows["build"] = []string{"$make"}
+	}
+	return flows, nil
+}
@@ -0,0 +1,44 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+)
+
+func TestDetect(t *testing.T) {
+	var dir = t.TempDir()
+	var files = map[string]string{
+		"go.mod":       "module example.com/demo\n",
+		"Makefile":     "GO ?= go\n\ntest: vet\n\tgo test ./...\n\nvet:\n\tgo vet ./...\n",
+		"package.json": `{"scripts": {"test": "echo \"Error: no test specified\" && exit 1", "lint": "eslint ."}}`,
+		"yarn.lock":    "",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	project, err := Detect(dir)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var expected = []iloyml.FlowDocument{
+		{Name: "build", Comment: "from go.mod", Steps: []iloyml.StepDocument{{Run: "$go build ./..."}}},
+		{Name: "test", Comment: "from Makefile", Steps: []iloyml.StepDocument{{Run: "$make test"}}},
+		{Name: "lint", Comment: "from go.mod, package.json", Steps: []iloyml.StepDocument{
+			{Run: "$go vet ./..."}, {Run: "$yarn run lint"},
+		}},
+	}
+	if !reflect.DeepEqual(project.Document.Flows, expected) {
+		t.Fatalf("got: %+v, want: %+v", project.Document.Flows, expected)
+	}
+	if want := []string{"go", "make", "yarn"}; !reflect.DeepEqual(project.Tools, want) {
+		t.Fatalf("got: %v, want: %v", project.Tools, want)
+	}
+}
@@ -0,0 +1,188 @@
+package scaffold
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+)
+
+// Importer converts a file of tasks from another tool into flows, along
+// with notes about anything which couldn't be translated.
+type Importer func(path string) (iloyml.Document, []string, error)
+
+// ImporterFor returns the importer for a file, from its name.
+func ImporterFor(path string) (Importer, error) {
+	name := filepath.Base(path)
+	switch {
+	case name == "Makefile" || name == "makefile" || name == "GNUmakefile" || filepath.Ext(name) == ".mk":
+		return ImportMakefile, nil
+	case name == "package.json":
+		return ImportPackageJSON, nil
+	case filepath.Ext(name) == ".yml" || filepath.Ext(name) == ".yaml":
+		return ImportWorkflow, nil
+	default:
+		return nil, fmt.Errorf("import '%s': unknown kind of file, expected a Makefile, package.json or GitHub workflow", path)
+	}
+}
+
+// Import converts each source into flows for a single project definition.
+// A directory of GitHub workflows imports every workflow in it, prefixing
+// flow names with the name of their workflow file.
+func Import(sources []string) (Project, error) {
+	var project Project
+	var notes []string
+	definedBy := make(map[string]string)
+
+	for _, source := range sources {
+		docs, err := importSource(source)
+		if err != nil {
+			return project, err
+		}
+
+		for _, doc := range docs {
+			if project.Document.Name == "" {
+				project.Document.Name = doc.document.Name
+			}
+			for _, note := range doc.notes {
+				notes = append(notes, fmt.Sprintf("%s: %s", filepath.Base(doc.path), note))
+			}
+
+			// Flows sharing a name with those of an earlier source are renamed
+			for _, flow := range doc.document.Flows {
+				if _, exists := definedBy[flow.Name]; exists {
+					prefixFlows(&doc.document, sourcePrefix(doc.path))
+					break
+				}
+			}
+
+			for _, flow := range doc.document.Flows {
+				if other, exists := definedBy[flow.Name]; exists {
+					return project, fmt.Errorf("import '%s': flow '%s' is already imported from '%s'", doc.path, flow.Name, other)
+				}
+				definedBy[flow.Name] = doc.path
+
+				if flow.Comment == "" {
+					flow.Comment = "from " + filepath.Base(doc.path)
+				} else {
+					flow.Comment = "from " + filepath.Base(doc.path) + "\n" + flow.Comment
+				}
+				project.Document.Flows = append(project.Document.Flows, flow)
+			}
+			project.Sources = append(project.Sources, doc.path)
+		}
+	}
+
+	project.Document.Comment = "Imported by ilo import from " + strings.Join(baseNames(project.Sources), ", ")
+	if len(notes) > 0 {
+		project.Document.Comment += "\n\n" + strings.Join(notes, "\n")
+	}
+	project.Tools = documentTools(project.Document)
+	return project, nil
+}
+
+type importedDoc struct {
+	path     string
+	document iloyml.Document
+	notes    []string
+}
+
+func importSource(source string) ([]importedDoc, error) {
+	stat, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("import '%s': %w", source, err)
+	}
+
+	if !stat.IsDir() {
+		importer, err := ImporterFor(source)
+		if err != nil {
+			return nil, err
+		}
+		doc, notes, err := importer(source)
+		return []importedDoc{{source, doc, notes}}, err
+	}
+
+	var paths []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, _ := filepath.Glob(filepath.Join(source, pattern))
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("import '%s': no workflows found", source)
+	}
+
+	var docs []importedDoc
+	for _, path := range paths {
+		doc, notes, err := ImportWorkflow(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(paths) > 1 {
+			prefixFlows(&doc, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		}
+		docs = append(docs, importedDoc{path, doc, notes})
+	}
+	return docs, nil
+}
+
+// sourcePrefix returns the prefix for the flows imported from a file.
+func sourcePrefix(path string) string {
+	switch importer, _ := ImporterFor(path); {
+	case filepath.Base(path) == "package.json":
+		return "npm"
+	case importer != nil && filepath.Ext(path) != ".yml" && filepath.Ext(path) != ".yaml":
+		return "make"
+	default:
+		return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+}
+
+// prefixFlows renames every flow in a document, and the flows they need,
+// with a prefix.
+func prefixFlows(doc *iloyml.Document, prefix string) {
+	for i := range doc.Flows {
+		doc.Flows[i].Name = prefix + "-" + doc.Flows[i].Name
+		for j := range doc.Flows[i].Needs {
+			doc.Flows[i].Needs[j] = prefix + "-" + doc.Flows[i].Needs[j]
+		}
+	}
+}
+
+// documentTools returns every tool referenced by the steps of a document.
+func documentTools(doc iloyml.Document) []string {
+	var tools []string
+	for _, flow := range doc.Flows {
+		for _, step := range flow.Steps {
+			fields := strings.Fields(step.Run)
+			if len(fields) == 0 {
+				continue
+			}
+			if tool, ok := strings.CutPrefix(fields[0], "$"); ok {
+				tools = appendUnique(tools, tool)
+			}
+		}
+	}
+	return tools
+}
+
+func baseNames(paths []string) []string {
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = filepath.Base(path)
+	}
+	return slices.Compact(names)
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
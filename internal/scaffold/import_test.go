@@ -0,0 +1,146 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+)
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	var path = filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportMakefile(t *testing.T) {
+	var path = writeFile(t, t.TempDir(), "Makefile", `GO ?= go
+BIN := bin/app
+
+build: $(BIN)
+
+$(BIN): main.go
+	@mkdir -p bin
+	$(GO) build -o $@ .
+
+test: build
+	$(GO) test ./... | tee test.log
+	-rm -f coverage.out
+	$(GO) vet $(shell go list ./...)
+
+%.o: %.c
+	cc -c $<
+`)
+
+	doc, notes, err := ImportMakefile(path)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var expected = []iloyml.FlowDocument{
+		{Name: "build", Needs: []string{"bin/app"}},
+		{Name: "bin/app", Steps: []iloyml.StepDocument{
+			{Comment: "depends on files: main.go"},
+			{Run: "mkdir -p bin"},
+			{Run: "$go build -o bin/app ."},
+		}},
+		{Name: "test", Needs: []string{"build"}, Steps: []iloyml.StepDocument{
+			{Run: `sh -c "go test ./... | tee test.log"`},
+			{Run: `sh -c "rm -f coverage.out || true"`},
+			{Comment: "not translated: $(GO) vet $(shell go list ./...)"},
+		}},
+	}
+	if !reflect.DeepEqual(doc.Flows, expected) {
+		t.Fatalf("got: %+v, want: %+v", doc.Flows, expected)
+	}
+	if want := []string{"not translated: rule for %.o"}; !reflect.DeepEqual(notes, want) {
+		t.Fatalf("got: %v, want: %v", notes, want)
+	}
+}
+
+func TestImportPackageJSON(t *testing.T) {
+	var path = writeFile(t, t.TempDir(), "package.json", `{
+  "scripts": {
+    "test": "npm run build && vitest run",
+    "build": "vite build && cp -r public/* dist",
+    "prebuild": "rm -rf dist",
+    "lint": "echo \"it's\" > lint.log && eslint ."
+  },
+  "devDependencies": {"vite": "5", "vitest": "1", "eslint": "8"}
+}`)
+
+	doc, _, err := ImportPackageJSON(path)
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var expected = []iloyml.FlowDocument{
+		{Name: "test", Needs: []string{"build"}, Steps: []iloyml.StepDocument{{Run: "$npx vitest run"}}},
+		{Name: "build", Needs: []string{"prebuild"}, Comment: "runs prebuild first", Steps: []iloyml.StepDocument{
+			{Run: "$npx vite build"},
+			{Run: `$npm exec -c "cp -r public/* dist"`},
+		}},
+		{Name: "lint", Steps: []iloyml.StepDocument{
+			{Comment: `not translated: echo "it's" > lint.log`},
+			{Run: "$npx eslint ."},
+		}},
+		{Name: "prebuild", Comment: "runs before build", Steps: []iloyml.StepDocument{{Run: "rm -rf dist"}}},
+	}
+	if !reflect.DeepEqual(doc.Flows, expected) {
+		t.Fatalf("got: %+v, want: %+v", doc.Flows, expected)
+	}
+}
+
+func TestImportWorkflow(t *testing.T) {
+	var dir = t.TempDir()
+	writeFile(t, dir, "Makefile", "build:\n\tgo build ./...\n")
+	writeFile(t, dir, ".github/workflows/ci.yml", `name: CI
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - name: Test
+        run: |
+          go vet ./...
+          go test \
+            ./...
+  deploy:
+    needs: build
+    steps:
+      - run: |
+          cd deploy
+          ./upload.sh ${{ secrets.TOKEN }}
+`)
+
+	project, err := Import([]string{filepath.Join(dir, "Makefile"), filepath.Join(dir, ".github/workflows")})
+	if err != nil {
+		t.Fatalf("got: %v, want: nil", err)
+	}
+
+	var expected = []iloyml.FlowDocument{
+		{Name: "build", Comment: "from Makefile", Steps: []iloyml.StepDocument{{Run: "$go build ./..."}}},
+		{Name: "ci-build", Comment: "from ci.yml\nruns-on: ubuntu-latest", Steps: []iloyml.StepDocument{
+			{Comment: "not translated: uses: actions/checkout@v4"},
+			{Comment: "Test"},
+			{Run: "$go vet ./..."},
+			{Run: "$go test ./..."},
+		}},
+		{Name: "ci-deploy", Needs: []string{"ci-build"}, Comment: "from ci.yml", Steps: []iloyml.StepDocument{
+			{Comment: "not translated:\ncd deploy\n./upload.sh ${{ secrets.TOKEN }}"},
+		}},
+	}
+	if !reflect.DeepEqual(project.Document.Flows, expected) {
+		t.Fatalf("got: %+v, want: %+v", project.Document.Flows, expected)
+	}
+	if want := []string{"go"}; !reflect.DeepEqual(project.Tools, want) {
+		t.Fatalf("got: %v, want: %v", project.Tools, want)
+	}
+}
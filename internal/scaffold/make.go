@@ -0,0 +1,279 @@
+package scaffold
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+)
+
+var (
+	makeAssignPattern = regexp.MustCompile(`^(?:export\s+|override\s+)*([A-Za-z_][A-Za-z0-9_.]*)\s*(\?|:::|::|:|\+|!)?=\s*(.*)$`)
+	makeRulePattern   = regexp.MustCompile(`^([^:#=\t][^:=]*?)\s*::?\s*(.*)$`)
+	makeVarPattern    = regexp.MustCompile(`\$(?:\(([^()]*)\)|\{([^{}]*)\}|([@<^+*?|]))`)
+)
+
+// makeDirectives start lines which control how a Makefile is read, and
+// can't be translated.
+var makeDirectives = []string{
+	"ifeq", "ifneq", "ifdef", "ifndef", "else", "endif", "include", "-include", "sinclude",
+	"define", "endef", "undefine", "vpath", "unexport", "export", "override",
+}
+
+type makeRule struct {
+	targets []string
+	prereqs []string
+	recipe  []string
+}
+
+// readMakefile reads a Makefile into its rules and variables, joining
+// continued lines. Lines which aren't rules or variables are returned as
+// untranslated.
+func readMakefile(path string) ([]makeRule, map[string]string, []string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer file.Close()
+
+	var rules []makeRule
+	vars := make(map[string]string)
+	var untranslated []string
+	var current *makeRule
+	inDefine := false
+
+	scanner := bufio.NewScanner(file)
+	var pending string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " "
+			continue
+		}
+		line, pending = pending+line, ""
+
+		if inDefine {
+			untranslated = append(untranslated, line)
+			inDefine = !strings.HasPrefix(strings.TrimSpace(line), "endef")
+			continue
+		}
+
+		if strings.HasPrefix(line, "\t") {
+			if current != nil {
+				current.recipe = append(current.recipe, strings.TrimPrefix(line, "\t"))
+			}
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if match := makeAssignPattern.FindStringSubmatch(trimmed); match != nil {
+			current = nil
+			switch match[2] {
+			case "+":
+				vars[match[1]] = strings.TrimSpace(vars[match[1]] + " " + match[3])
+			case "?":
+				if _, exists := vars[match[1]]; !exists {
+					vars[match[1]] = match[3]
+				}
+			case "!":
+				// Assigned from a shell command, which can't be known here
+				untranslated = append(untranslated, trimmed)
+			default:
+				vars[match[1]] = match[3]
+			}
+			continue
+		}
+
+		if word, _, _ := strings.Cut(trimmed, " "); slices.Contains(makeDirectives, word) {
+			current = nil
+			untranslated = append(untranslated, trimmed)
+			inDefine = word == "define"
+			continue
+		}
+
+		if match := makeRulePattern.FindStringSubmatch(trimmed); match != nil {
+			deps, inline, hasInline := strings.Cut(match[2], ";")
+			rules = append(rules, makeRule{
+				targets: strings.Fields(match[1]),
+				prereqs: strings.Fields(strings.ReplaceAll(deps, "|", " ")),
+			})
+			current = &rules[len(rules)-1]
+			if hasInline && strings.TrimSpace(inline) != "" {
+				current.recipe = append(current.recipe, strings.TrimSpace(inline))
+			}
+			continue
+		}
+
+		current = nil
+		untranslated = append(untranslated, trimmed)
+	}
+
+	return rules, vars, untranslated, scanner.Err()
+}
+
+// expandMake expands the variables in text. It returns false if text uses
+// a variable which isn't defined, or a function.
+func expandMake(text string, vars map[string]string, target string, prereqs []string, depth int) (string, bool) {
+	ok := true
+	text = strings.ReplaceAll(text, "$$", "\x00")
+
+	text = makeVarPattern.ReplaceAllStringFunc(text, func(ref string) string {
+		match := makeVarPattern.FindStringSubmatch(ref)
+		name := match[1] + match[2] + match[3]
+
+		switch name {
+		case "@":
+			return target
+		case "<":
+			if len(prereqs) > 0 {
+				return prereqs[0]
+			}
+			return ""
+		case "^", "+":
+			return strings.Join(prereqs, " ")
+		case "MAKE":
+			return "make"
+		}
+
+		value, exists := vars[name]
+		if !exists || depth > 10 {
+			ok = false
+			return ref
+		}
+
+		value, valueOk := expandMake(value, vars, target, prereqs, depth+1)
+		ok = ok && valueOk
+		return value
+	})
+
+	return strings.ReplaceAll(text, "\x00", "$"), ok
+}
+
+// ImportMakefile converts the targets of a Makefile into flows. A target's
+// prerequisites which are also targets become flows it needs, and each line
+// of its recipe becomes a step.
+func ImportMakefile(path string) (iloyml.Document, []string, error) {
+	rules, vars, untranslated, err := readMakefile(path)
+	if err != nil {
+		return iloyml.Document{}, nil, fmt.Errorf("import '%s': %w", path, err)
+	}
+
+	var doc iloyml.Document
+	var notes []string
+	for _, line := range untranslated {
+		notes = append(notes, "not translated: "+line)
+	}
+
+	targets := make(map[string]bool)
+	var expanded []makeRule
+	for _, rule := range rules {
+		var ruleTargets []string
+		for _, target := range rule.targets {
+			target, ok := expandMake(target, vars, "", nil, 0)
+			if !ok || strings.Contains(target, "%") || strings.HasPrefix(target, ".") {
+				continue
+			}
+			ruleTargets = append(ruleTargets, strings.Fields(target)...)
+		}
+		if len(ruleTargets) == 0 {
+			if !strings.HasPrefix(rule.targets[0], ".") {
+				notes = append(notes, "not translated: rule for "+strings.Join(rule.targets, " "))
+			}
+			continue
+		}
+
+		rule.targets = ruleTargets
+		for _, target := range ruleTargets {
+			targets[target] = true
+		}
+		expanded = append(expanded, rule)
+	}
+
+	// Flows are kept in the order their targets first appear
+	var order []string
+	flows := make(map[string]*iloyml.FlowDocument)
+	for _, rule := range expanded {
+		for _, target := range rule.targets {
+			flow, exists := flows[target]
+			if !exists {
+				flow = &iloyml.FlowDocument{Name: target}
+				flows[target] = flow
+				order = append(order, target)
+			}
+			importMakeRule(flow, rule, target, vars, targets)
+		}
+	}
+
+	for _, target := range order {
+		doc.Flows = append(doc.Flows, *flows[target])
+	}
+
+	return doc, notes, nil
+}
+
+func importMakeRule(flow *iloyml.FlowDocument, rule makeRule, target string, vars map[string]string, targets map[string]bool) {
+	var prereqs, files []string
+	for _, prereq := range rule.prereqs {
+		expanded, ok := expandMake(prereq, vars, target, nil, 0)
+		if !ok {
+			flow.Steps = append(flow.Steps, iloyml.StepDocument{Comment: "not translated: prerequisite " + prereq})
+			continue
+		}
+		for _, name := range strings.Fields(expanded) {
+			prereqs = append(prereqs, name)
+			if targets[name] {
+				if !slices.Contains(flow.Needs, name) {
+					flow.Needs = append(flow.Needs, name)
+				}
+			} else {
+				files = append(files, name)
+			}
+		}
+	}
+	if len(files) > 0 {
+		flow.Steps = append(flow.Steps, iloyml.StepDocument{Comment: "depends on files: " + strings.Join(files, " ")})
+	}
+
+	for _, line := range rule.recipe {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			flow.Steps = append(flow.Steps, iloyml.StepDocument{Comment: strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))})
+			continue
+		}
+		flow.Steps = append(flow.Steps, translateMakeLine(line, vars, target, prereqs))
+	}
+}
+
+// translateMakeLine turns a line of a recipe into a step, or a comment if
+// it can't be translated.
+func translateMakeLine(line string, vars map[string]string, target string, prereqs []string) iloyml.StepDocument {
+	untranslated := iloyml.StepDocument{Comment: "not translated: " + strings.TrimSpace(line)}
+
+	command := strings.TrimSpace(line)
+	ignoreErrors := false
+	for len(command) > 0 && strings.ContainsRune("@-+", rune(command[0])) {
+		ignoreErrors = ignoreErrors || command[0] == '-'
+		command = strings.TrimSpace(command[1:])
+	}
+
+	command, ok := expandMake(command, vars, target, prereqs, 0)
+	if !ok {
+		return untranslated
+	}
+	if ignoreErrors {
+		command += " || true"
+	}
+
+	run, ok := translateCommand(command, "sh")
+	if !ok {
+		return untranslated
+	}
+	return iloyml.StepDocument{Run: run}
+}
@@ -0,0 +1,141 @@
+package scaffold
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"gopkg.in/yaml.v3"
+)
+
+// npmRunPattern matches a command which runs another script.
+var npmRunPattern = regexp.MustCompile(`^(?:npm|pnpm|yarn|bun)(?: run| run-script)? ([A-Za-z0-9:_.-]+)$`)
+
+// ImportPackageJSON converts the scripts of a package.json into flows. Each
+// command joined by && becomes a step, scripts run at the start of a
+// script become flows it needs, and pre and post scripts are run before
+// and after the script they belong to, as npm does.
+func ImportPackageJSON(path string) (iloyml.Document, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return iloyml.Document{}, nil, fmt.Errorf("import '%s': %w", path, err)
+	}
+
+	// JSON is YAML, and a node keeps the order of the scripts
+	var manifest struct {
+		Name            string
+		Scripts         yaml.Node
+		Dependencies    map[string]string
+		DevDependencies map[string]string `yaml:"devDependencies"`
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return iloyml.Document{}, nil, fmt.Errorf("import '%s': %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	manager := nodeManager(dir)
+	isPackage := func(program string) bool {
+		_, dependency := manifest.Dependencies[program]
+		_, devDependency := manifest.DevDependencies[program]
+		return dependency || devDependency || exists(dir, filepath.Join("node_modules", ".bin", program))
+	}
+
+	scripts := make(map[string]string)
+	var names []string
+	for i := 0; i+1 < len(manifest.Scripts.Content); i += 2 {
+		name := manifest.Scripts.Content[i].Value
+		scripts[name] = manifest.Scripts.Content[i+1].Value
+		names = append(names, name)
+	}
+
+	doc := iloyml.Document{Name: manifest.Name}
+	for _, name := range names {
+		if _, ok := hookedScript(name, scripts); ok {
+			// Run along with the script it belongs to
+			continue
+		}
+
+		flow := iloyml.FlowDocument{Name: name}
+		if _, ok := scripts["pre"+name]; ok {
+			flow.Needs = append(flow.Needs, "pre"+name)
+			flow.Comment = fmt.Sprintf("runs pre%s first", name)
+		}
+
+		leading := true
+		for _, command := range splitAnd(scripts[name]) {
+			if match := npmRunPattern.FindStringSubmatch(command); match != nil && leading {
+				if _, ok := scripts[match[1]]; ok && match[1] != name {
+					flow.Needs = append(flow.Needs, match[1])
+					continue
+				}
+			}
+			leading = false
+			flow.Steps = append(flow.Steps, translateScript(command, manager, isPackage))
+		}
+
+		if post, ok := scripts["post"+name]; ok {
+			flow.Steps = append(flow.Steps, iloyml.StepDocument{Comment: "from post" + name})
+			for _, command := range splitAnd(post) {
+				flow.Steps = append(flow.Steps, translateScript(command, manager, isPackage))
+			}
+		}
+		doc.Flows = append(doc.Flows, flow)
+	}
+
+	// Pre scripts are needed as flows of their own
+	for _, name := range names {
+		if base, ok := hookedScript(name, scripts); ok && strings.HasPrefix(name, "pre") {
+			flow := iloyml.FlowDocument{Name: name, Comment: "runs before " + base}
+			for _, command := range splitAnd(scripts[name]) {
+				flow.Steps = append(flow.Steps, translateScript(command, manager, isPackage))
+			}
+			doc.Flows = append(doc.Flows, flow)
+		}
+	}
+
+	return doc, nil, nil
+}
+
+// hookedScript returns the script which a pre or post script belongs to.
+func hookedScript(name string, scripts map[string]string) (string, bool) {
+	for _, prefix := range []string{"pre", "post"} {
+		if base, ok := strings.CutPrefix(name, prefix); ok {
+			if _, exists := scripts[base]; exists {
+				return base, true
+			}
+		}
+	}
+	return "", false
+}
+
+// translateScript turns a command from a script into a step. Programs
+// installed by the project are only on PATH when run through the package
+// manager.
+func translateScript(command string, manager string, isPackage func(string) bool) iloyml.StepDocument {
+	untranslated := iloyml.StepDocument{Comment: "not translated: " + command}
+
+	program, _, _ := strings.Cut(command, " ")
+	if !needsShell(command) {
+		run, _ := translateCommand(command, "sh")
+		switch {
+		case !isPackage(program):
+			return iloyml.StepDocument{Run: run}
+		case manager == "npm":
+			return iloyml.StepDocument{Run: "$npx " + command}
+		default:
+			return iloyml.StepDocument{Run: fmt.Sprintf("$%s exec %s", manager, command)}
+		}
+	}
+
+	quoted, ok := quote(command)
+	if !ok {
+		return untranslated
+	}
+	if manager == "npm" {
+		return iloyml.StepDocument{Run: "$npm exec -c " + quoted}
+	}
+	return iloyml.StepDocument{Run: fmt.Sprintf("$%s exec sh -c %s", manager, quoted)}
+}
@@ -0,0 +1,89 @@
+package scaffold
+
+import (
+	"slices"
+	"strings"
+)
+
+// knownTools are programs which are referenced as tools when they are run
+// directly by an imported command.
+var knownTools = []string{
+	"bun", "cargo", "cmake", "ctest", "deno", "go", "gradle", "make", "mvn",
+	"node", "npm", "npx", "pip", "pip3", "pnpm", "python", "python3", "ruff", "yarn",
+}
+
+// shellSyntax is text which only a shell can interpret, so commands using
+// it can't be run directly as a step.
+var shellSyntax = []string{"|", "&", ";", "<", ">", "$", "`", "*", "?", "~", "(", ")", "\\", "\n", "[", "]"}
+
+// needsShell reports whether a command uses shell syntax, or sets
+// environment variables before running a program.
+func needsShell(command string) bool {
+	for _, syntax := range shellSyntax {
+		if strings.Contains(command, syntax) {
+			return true
+		}
+	}
+
+	fields := strings.Fields(command)
+	return len(fields) > 0 && strings.Contains(fields[0], "=")
+}
+
+// quote quotes an argument for a step, returning false if it contains both
+// kinds of quote, as steps have no way of escaping them.
+func quote(arg string) (string, bool) {
+	switch {
+	case !strings.Contains(arg, `"`):
+		return `"` + arg + `"`, true
+	case !strings.Contains(arg, "'"):
+		return "'" + arg + "'", true
+	default:
+		return "", false
+	}
+}
+
+// translateCommand turns a shell command into the text of a run step,
+// running it through shell if it uses shell syntax. It returns false if the
+// command can't be written as a step.
+func translateCommand(command string, shell string) (string, bool) {
+	command = strings.TrimSpace(command)
+	if command == "" || strings.Contains(command, "${{") {
+		return "", false
+	}
+
+	if needsShell(command) {
+		quoted, ok := quote(command)
+		if !ok {
+			return "", false
+		}
+		return shell + " -c " + quoted, true
+	}
+
+	program, args, _ := strings.Cut(command, " ")
+	if slices.Contains(knownTools, program) {
+		program = "$" + program
+	}
+	return strings.TrimSpace(program + " " + args), true
+}
+
+// splitAnd splits a command on the && operators outside quotes.
+func splitAnd(command string) []string {
+	var parts []string
+	var quote rune
+	start := 0
+
+	for i, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case strings.HasPrefix(command[i:], "&&"):
+			parts = append(parts, strings.TrimSpace(command[start:i]))
+			start = i + 2
+		}
+	}
+	return append(parts, strings.TrimSpace(command[start:]))
+}
@@ -0,0 +1,189 @@
+package scaffold
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"gopkg.in/yaml.v3"
+)
+
+type workflowStep struct {
+	Name             string
+	Uses             string
+	Run              string
+	If               string
+	Shell            string
+	WorkingDirectory string `yaml:"working-directory"`
+	Env              map[string]string
+}
+
+type workflowJob struct {
+	Name     string
+	Needs    yaml.Node
+	If       string
+	RunsOn   yaml.Node `yaml:"runs-on"`
+	Strategy yaml.Node
+	Env      map[string]string
+	Steps    []workflowStep
+}
+
+// scriptStatePattern matches shell lines whose effect lasts for the rest
+// of a script, so the script must run as a whole.
+var scriptStatePattern = regexp.MustCompile(`^(cd|export|source|\.|set|if|for|while|case|until|function)\b|^[A-Za-z_][A-Za-z0-9_]*=|\(\)\s*\{`)
+
+// ImportWorkflow converts the jobs of a GitHub Actions workflow into flows,
+// with the jobs they need. The run steps of each job become steps, while
+// actions, conditions and other settings are kept as comments.
+func ImportWorkflow(path string) (iloyml.Document, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return iloyml.Document{}, nil, fmt.Errorf("import '%s': %w", path, err)
+	}
+
+	var workflow struct {
+		Name string
+		Jobs yaml.Node
+	}
+	if err := yaml.Unmarshal(data, &workflow); err != nil {
+		return iloyml.Document{}, nil, fmt.Errorf("import '%s': %w", path, err)
+	}
+	if workflow.Jobs.Kind != yaml.MappingNode {
+		return iloyml.Document{}, nil, fmt.Errorf("import '%s': no jobs found", path)
+	}
+
+	doc := iloyml.Document{Name: workflow.Name}
+	for i := 0; i+1 < len(workflow.Jobs.Content); i += 2 {
+		var job workflowJob
+		if err := workflow.Jobs.Content[i+1].Decode(&job); err != nil {
+			return doc, nil, fmt.Errorf("import '%s' job '%s': %w", path, workflow.Jobs.Content[i].Value, err)
+		}
+		doc.Flows = append(doc.Flows, importJob(workflow.Jobs.Content[i].Value, job))
+	}
+
+	return doc, nil, nil
+}
+
+func nodeValues(node yaml.Node) []string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}
+	case yaml.SequenceNode:
+		var values []string
+		for _, item := range node.Content {
+			values = append(values, item.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+func importJob(id string, job workflowJob) iloyml.FlowDocument {
+	flow := iloyml.FlowDocument{Name: id, Description: job.Name, Needs: nodeValues(job.Needs)}
+
+	var notes []string
+	if runsOn := nodeValues(job.RunsOn); len(runsOn) > 0 {
+		notes = append(notes, "runs-on: "+strings.Join(runsOn, ", "))
+	}
+	if job.If != "" {
+		notes = append(notes, "not translated: if: "+job.If)
+	}
+	if job.Strategy.Kind != 0 {
+		notes = append(notes, "not translated: strategy")
+	}
+	for _, name := range sortedKeys(job.Env) {
+		notes = append(notes, fmt.Sprintf("not translated: env %s=%s", name, job.Env[name]))
+	}
+	flow.Comment = strings.Join(notes, "\n")
+
+	for _, step := range job.Steps {
+		flow.Steps = append(flow.Steps, importWorkflowStep(step)...)
+	}
+	return flow
+}
+
+func importWorkflowStep(step workflowStep) []iloyml.StepDocument {
+	var notes []string
+	if step.Name != "" {
+		notes = append(notes, step.Name)
+	}
+	if step.Uses != "" {
+		notes = append(notes, "not translated: uses: "+step.Uses)
+	}
+	if step.If != "" {
+		notes = append(notes, "not translated: if: "+step.If)
+	}
+	if step.WorkingDirectory != "" {
+		notes = append(notes, "not translated: working-directory: "+step.WorkingDirectory)
+	}
+	for _, name := range sortedKeys(step.Env) {
+		notes = append(notes, fmt.Sprintf("not translated: env %s=%s", name, step.Env[name]))
+	}
+
+	var steps []iloyml.StepDocument
+	if len(notes) > 0 {
+		steps = append(steps, iloyml.StepDocument{Comment: strings.Join(notes, "\n")})
+	}
+	if step.Run == "" {
+		return steps
+	}
+
+	shell := "bash"
+	if step.Shell != "" {
+		shell, _, _ = strings.Cut(step.Shell, " ")
+	}
+
+	lines := scriptLines(step.Run)
+	whole := len(lines) > 1
+	if whole {
+		whole = false
+		for _, line := range lines {
+			whole = whole || scriptStatePattern.MatchString(line)
+		}
+	}
+
+	if whole {
+		// Lines depend on each other, so they can't be separate steps
+		script := strings.Join(lines, "\n")
+		if quoted, ok := quote(script); ok && !strings.Contains(script, "${{") {
+			return append(steps, iloyml.StepDocument{Run: shell + " -ec " + quoted})
+		}
+		return append(steps, iloyml.StepDocument{Comment: "not translated:\n" + script})
+	}
+
+	for _, line := range lines {
+		if comment, ok := strings.CutPrefix(line, "#"); ok {
+			steps = append(steps, iloyml.StepDocument{Comment: strings.TrimSpace(comment)})
+		} else if run, ok := translateCommand(line, shell); ok {
+			steps = append(steps, iloyml.StepDocument{Run: run})
+		} else {
+			steps = append(steps, iloyml.StepDocument{Comment: "not translated: " + line})
+		}
+	}
+	return steps
+}
+
+// scriptLines splits a script into its non-empty lines, joining lines
+// continued with a backslash.
+func scriptLines(script string) []string {
+	var lines []string
+	var pending string
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasSuffix(line, "\\") {
+			pending += strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " "
+			continue
+		}
+
+		line, pending = strings.TrimSpace(pending+line), ""
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if pending = strings.TrimSpace(pending); pending != "" {
+		lines = append(lines, pending)
+	}
+	return lines
+}
@@ -1,42 +1,129 @@
 package server
 
 import (
+	"context"
 	"log/slog"
+	"path/filepath"
+	"sync"
 	"time"
 
-	"github.com/fourls/ilo/internal/data"
+	"github.com/fourls/ilo/internal/data/provide"
 	"github.com/fourls/ilo/internal/data/toolbox"
 	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/history"
 	"github.com/fourls/ilo/internal/ilofile"
+	"github.com/fourls/ilo/internal/runlog"
 )
 
-type scheduledFlow struct {
-	flow     ilofile.Flow
-	schedule data.Schedule
+// Clock tells the daemon the time, so that tests can control when flows
+// are due.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
 }
 
 type IloDaemon struct {
-	ticker        *time.Ticker
-	log           *slog.Logger
-	toolbox       toolbox.Toolbox
-	flowSchedules []scheduledFlow
+	mu               sync.Mutex
+	ticker           *time.Ticker
+	clock            Clock
+	log              *slog.Logger
+	toolbox          toolbox.Toolbox
+	logs             runlog.Store
+	history          history.Store
+	retention        runlog.Retention
+	historyRetention history.Retention
+	scheduleFile     provide.Provider[ScheduleFile]
+	flowSchedules    []scheduledFlow
+	// scheduleState is the saved state of schedules, by ID, which is kept
+	// for declared schedules while their projects can't be read.
+	scheduleState map[string]ScheduleState
+	// runs holds the unfinished runs of each schedule, by schedule ID.
+	runs        map[string]*scheduleRuns
+	projectFile provide.Provider[ProjectFile]
+	projects    []registeredProject
+	// runStep runs each step of a flow, which is exec.RunStep if nil.
+	runStep exec.StepExecutorFunc
 }
 
 func (d *IloDaemon) Run() {
-	d.ticker = time.NewTicker(time.Minute)
+	// Schedules can run every second
+	d.ticker = time.NewTicker(time.Second)
 	go d.worker()
 }
 
-func (d *IloDaemon) RunFlow(flow ilofile.Flow) {
-	observer := newObserver(flow.Project, d.log)
-	go exec.RunFlow(flow, exec.RunStep, d.toolbox, &observer)
+func (d *IloDaemon) now() time.Time {
+	return d.clock.Now()
 }
 
-func (d *IloDaemon) ScheduleFlow(flow ilofile.Flow, schedule data.Schedule) {
-	d.flowSchedules = append(d.flowSchedules, scheduledFlow{
-		flow:     flow,
-		schedule: schedule,
-	})
+// RunFlow starts running a flow in the background, after the flows it
+// needs, recording trigger as what started it.
+func (d *IloDaemon) RunFlow(flow ilofile.Flow, trigger history.Trigger) {
+	d.startFlow(context.Background(), flow, trigger, nil)
+}
+
+// startFlow starts running a flow in the background until ctx is done,
+// calling done once it finishes, or straight away if it can't start.
+func (d *IloDaemon) startFlow(ctx context.Context, flow ilofile.Flow, trigger history.Trigger, done func()) {
+	if done == nil {
+		done = func() {}
+	}
+
+	projectTools, err := toolbox.LoadProject(filepath.Dir(flow.Project.Path), flow.Project.Toolbox)
+	if err != nil {
+		d.log.Error("Flow not started", "project", flow.Project.Path, "flow", flow.Name, "error", err)
+		done()
+		return
+	}
+	tb := toolbox.Merge(projectTools, d.toolbox)
+
+	flows, err := flow.Project.Plan(flow.Name)
+	if err != nil {
+		d.log.Error("Flow not started", "project", flow.Project.Path, "flow", flow.Name, "error", err)
+		done()
+		return
+	}
+
+	if err := exec.CheckTools(flow.Project, flows, tb); err != nil {
+		d.log.Error("Flow not started", "project", flow.Project.Path, "flow", flow.Name, "error", err)
+		done()
+		return
+	}
+
+	runID := exec.NewRunID()
+	observer := newObserver(flow.Project, d.log)
+	observers := exec.NewMultiObserver(&observer, history.NewObserver(d.history, flow.Project, trigger))
+
+	if writer, err := d.logs.NewWriter(runID, flow.Project); err != nil {
+		d.log.Warn("Run not logged", "run", runID, "error", err)
+	} else {
+		observers.Add(writer)
+	}
+
+	runStep := d.runStep
+	if runStep == nil {
+		runStep = exec.RunStep
+	}
+
+	go func() {
+		defer done()
+
+		exec.RunPlanContext(ctx, runID, flows, runStep, tb, observers)
+
+		if err := observers.Close(); err != nil {
+			d.log.Warn("Run log incomplete", "run", runID, "error", err)
+		}
+		if _, err := d.logs.Prune(d.retention, d.now()); err != nil {
+			d.log.Warn("Run logs not pruned", "error", err)
+		}
+		if _, err := d.history.Prune(d.historyRetention, d.now()); err != nil {
+			d.log.Warn("History not pruned", "error", err)
+		}
+	}()
 }
 
 func (d *IloDaemon) worker() {
@@ -44,15 +131,49 @@ func (d *IloDaemon) worker() {
 		return
 	}
 
-	for time := range d.ticker.C {
-		d.tick(time)
+	for range d.ticker.C {
+		d.tick(d.now())
 	}
 }
 
+// tick starts the flows which are due at now, following their catch-up
+// policies for any runs missed since they were last due.
 func (d *IloDaemon) tick(now time.Time) {
-	for _, entry := range d.flowSchedules {
-		if entry.schedule.Match(now) {
-			d.RunFlow(entry.flow)
+	d.refreshProjects(now)
+
+	type dueRun struct {
+		entry ScheduleEntry
+		run   *scheduledRun
+	}
+
+	d.mu.Lock()
+	var due []dueRun
+	for i := range d.flowSchedules {
+		scheduled := &d.flowSchedules[i]
+		if scheduled.next.IsZero() || now.Before(scheduled.next) {
+			continue
+		}
+
+		runs, missed := scheduled.dueRuns(now)
+		if missed > 0 {
+			d.log.Warn("Scheduled runs missed", "schedule", scheduled.entry.ID, "flow", scheduled.entry.Flow,
+				"missed", missed, "catchup", scheduled.entry.CatchUp)
 		}
+		if runs > 0 {
+			if run := d.admitRun(scheduled.entry); run != nil {
+				due = append(due, dueRun{entry: scheduled.entry, run: run})
+			}
+		}
+		// Further missed runs follow one after another, rather than
+		// overlapping with each other
+		if runs > 1 {
+			d.scheduleRuns(scheduled.entry.ID).catchUp += runs - 1
+		}
+		scheduled.reschedule(now)
+	}
+	d.mu.Unlock()
+
+	for _, run := range due {
+		d.runScheduled(run.entry, run.run)
 	}
 }
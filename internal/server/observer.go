@@ -3,53 +3,47 @@ package server
 import (
 	"log/slog"
 
+	"github.com/fourls/ilo/internal/exec"
 	"github.com/fourls/ilo/internal/ilofile"
 )
 
 type StructuredObserver struct {
-	logger    *slog.Logger
-	project   *ilofile.Definition
-	flow      *ilofile.Flow
-	step      ilofile.Step
-	stepIndex int
+	logger *slog.Logger
 }
 
 func newObserver(project *ilofile.Definition, logger *slog.Logger) StructuredObserver {
-	return StructuredObserver{project: project, logger: logger.With("project", project.Path), stepIndex: -1}
+	return StructuredObserver{logger: logger.With("project", project.Path)}
 }
 
-func (o *StructuredObserver) FlowEntered(f *ilofile.Flow) {
-	o.flow = f
-	o.stepIndex = -1
-	o.logger.Info("Flow entered", "flow", o.flow.Name)
+func (o *StructuredObserver) FlowEntered(e exec.Event) {
+	o.logger.Info("Flow entered", "run", e.RunID, "flow", e.Flow.Name)
 }
 
-func (o *StructuredObserver) StepEntered(s ilofile.Step) {
-	o.step = s
-	o.stepIndex += 1
-	o.logger.Info("Step entered", "flow", o.flow.Name, "step", o.stepIndex, "stepText", o.step.String())
+func (o *StructuredObserver) StepEntered(e exec.Event) {
+	o.logger.Info("Step entered", "run", e.RunID, "flow", e.Flow.Name, "step", e.StepIndex, "stepText", e.Step.String())
 }
 
-func (o *StructuredObserver) StepOutput(text string) {
-	o.logger.Debug("> "+text, "flow", o.flow.Name, "step", o.stepIndex)
+func (o *StructuredObserver) StepOutput(e exec.Event) {
+	o.logger.Debug("> "+e.Text, "run", e.RunID, "flow", e.Flow.Name, "step", e.StepIndex, "stream", e.Stream.String())
 }
 
-func (o *StructuredObserver) StepPassed() {
-	o.logger.Info("Step passed", "flow", o.flow.Name, "step", o.stepIndex)
-	o.step = nil
+func (o *StructuredObserver) StepPassed(e exec.Event) {
+	o.logger.Info("Step passed", "run", e.RunID, "flow", e.Flow.Name, "step", e.StepIndex, "duration", e.Duration)
 }
 
-func (o *StructuredObserver) StepFailed(err error) {
-	o.logger.Info("Step failed", "flow", o.flow.Name, "step", o.stepIndex, "error", err)
-	o.step = nil
+func (o *StructuredObserver) StepFailed(e exec.Event) {
+	o.logger.Info("Step failed", "run", e.RunID, "flow", e.Flow.Name, "step", e.StepIndex, "duration", e.Duration,
+		"exitCode", e.ExitCode, "error", e.Err)
 }
 
-func (o *StructuredObserver) FlowPassed() {
-	o.logger.Info("Flow passed", "flow", o.flow.Name)
-	o.flow = nil
+func (o *StructuredObserver) FlowPassed(e exec.Event) {
+	o.logger.Info("Flow passed", "run", e.RunID, "flow", e.Flow.Name, "duration", e.Duration)
 }
 
-func (o *StructuredObserver) FlowFailed() {
-	o.logger.Info("Flow failed", "flow", o.flow.Name, "step", o.stepIndex)
-	o.flow = nil
+func (o *StructuredObserver) FlowFailed(e exec.Event) {
+	if e.Skipped {
+		o.logger.Info("Flow skipped", "run", e.RunID, "flow", e.Flow.Name, "reason", e.Err)
+		return
+	}
+	o.logger.Info("Flow failed", "run", e.RunID, "flow", e.Flow.Name, "duration", e.Duration)
 }
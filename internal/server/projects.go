@@ -0,0 +1,458 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/ilofile"
+	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectEntry is a project definition file registered with the server.
+type ProjectEntry struct {
+	// ID identifies the project in the API, and is the same whenever the
+	// same path is registered.
+	ID    string    `yaml:"id" json:"id"`
+	Path  string    `yaml:"path" json:"path"`
+	Added time.Time `yaml:"added" json:"added"`
+}
+
+// UnmarshalYAML reads a project entry, which may be a path on its own.
+func (e *ProjectEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		e.Path = node.Value
+		e.ID = projectID(e.Path)
+		return nil
+	}
+
+	type entryFields ProjectEntry
+	return node.Decode((*entryFields)(e))
+}
+
+// ProjectStatus describes a registered project as it was last read.
+type ProjectStatus struct {
+	ProjectEntry
+	Name  string       `json:"name,omitempty"`
+	Flows []FlowStatus `json:"flows"`
+	// Error is why the project definition couldn't be read. Its flows are
+	// as they were when it last could be.
+	Error string `json:"error,omitempty"`
+}
+
+// FlowStatus describes a flow of a registered project and its schedules.
+type FlowStatus struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Needs       []string         `json:"needs,omitempty"`
+	Schedules   []ScheduleStatus `json:"schedules"`
+}
+
+// ProjectFile is the file registered projects are saved to.
+type ProjectFile struct {
+	Projects []ProjectEntry `yaml:"projects"`
+}
+
+var (
+	// ErrProjectNotFound is returned for a project ID which isn't
+	// registered.
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrFlowNotFound is returned for a flow which a project doesn't have.
+	ErrFlowNotFound = errors.New("flow not found")
+	// ErrProjectInvalid is returned when running a flow of a project whose
+	// definition can't be read.
+	ErrProjectInvalid = errors.New("project definition is invalid")
+)
+
+// registeredProject is a project definition file which the server watches
+// for changes.
+type registeredProject struct {
+	entry ProjectEntry
+	// definition is the project as it was last read successfully, and err
+	// is why it couldn't be read since.
+	definition *ilofile.Definition
+	err        error
+	// modified and size are what the file was when it was last read, to
+	// tell when it changes.
+	modified time.Time
+	size     int64
+}
+
+func projectID(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:4])
+}
+
+// loadProjects reads the saved list of registered projects. They are read
+// on the next refresh.
+func (d *IloDaemon) loadProjects() error {
+	file, err := d.projectFile.Load("projects", provide.YamlUnmarshal[ProjectFile])
+	if err != nil {
+		return fmt.Errorf("load projects: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.projects = nil
+	for _, entry := range file.Projects {
+		d.projects = append(d.projects, registeredProject{entry: entry})
+	}
+	return nil
+}
+
+// saveProjects writes the registered projects to disk. The lock must be
+// held.
+func (d *IloDaemon) saveProjects() error {
+	file := ProjectFile{Projects: make([]ProjectEntry, len(d.projects))}
+	for i, project := range d.projects {
+		file.Projects[i] = project.entry
+	}
+
+	if err := d.projectFile.Save("projects", &file, provide.YamlMarshal); err != nil {
+		return fmt.Errorf("save projects: %w", err)
+	}
+	return nil
+}
+
+// addProject registers the project definition at path if it isn't
+// already, returning its index. The lock must be held.
+func (d *IloDaemon) addProject(path string) (int, bool, error) {
+	if i := d.findProject(path); i >= 0 {
+		return i, false, nil
+	}
+
+	entry := ProjectEntry{ID: projectID(path), Path: path, Added: d.now().UTC().Truncate(time.Second)}
+	d.projects = append(d.projects, registeredProject{entry: entry})
+	if err := d.saveProjects(); err != nil {
+		d.projects = d.projects[:len(d.projects)-1]
+		return -1, false, err
+	}
+	return len(d.projects) - 1, true, nil
+}
+
+// RegisterProject watches the project definition at path, running its
+// flows on the schedules declared in it, and reports whether it wasn't
+// registered already. A definition which can't be read is still
+// registered, and is read again once it changes.
+func (d *IloDaemon) RegisterProject(path string) (ProjectStatus, bool, error) {
+	if _, err := os.Stat(path); err != nil {
+		return ProjectStatus{}, false, err
+	}
+
+	d.mu.Lock()
+	_, added, err := d.addProject(path)
+	d.mu.Unlock()
+	if err != nil {
+		return ProjectStatus{}, false, err
+	}
+
+	d.refreshProjects(d.now())
+
+	status, err := d.Project(projectID(path))
+	return status, added, err
+}
+
+// UnregisterProject stops watching a project, and removes its schedules.
+// Runs which have already started carry on.
+func (d *IloDaemon) UnregisterProject(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.findProjectID(id)
+	if i < 0 {
+		return ErrProjectNotFound
+	}
+
+	removed := d.projects[i]
+	d.projects = slices.Delete(d.projects, i, i+1)
+	if err := d.saveProjects(); err != nil {
+		d.projects = slices.Insert(d.projects, i, removed)
+		return err
+	}
+
+	d.flowSchedules = slices.DeleteFunc(d.flowSchedules, func(s scheduledFlow) bool {
+		if s.entry.Project != removed.entry.Path {
+			return false
+		}
+		delete(d.scheduleState, s.entry.ID)
+		return true
+	})
+	return d.saveSchedules()
+}
+
+// Projects returns every registered project, in the order they were
+// registered.
+func (d *IloDaemon) Projects() []ProjectStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	statuses := make([]ProjectStatus, len(d.projects))
+	for i := range d.projects {
+		statuses[i] = d.projectStatus(&d.projects[i])
+	}
+	return statuses
+}
+
+// Project returns the registered project with an ID.
+func (d *IloDaemon) Project(id string) (ProjectStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.findProjectID(id)
+	if i < 0 {
+		return ProjectStatus{}, ErrProjectNotFound
+	}
+	return d.projectStatus(&d.projects[i]), nil
+}
+
+// ProjectFlow returns a flow of a registered project, as it was last read.
+// Flows can't be used while the project's definition is invalid.
+func (d *IloDaemon) ProjectFlow(id string, flowName string) (ilofile.Flow, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.findProjectID(id)
+	if i < 0 {
+		return ilofile.Flow{}, ErrProjectNotFound
+	}
+	return d.projects[i].flow(flowName)
+}
+
+// FlowStatus describes a flow of a registered project.
+func (d *IloDaemon) FlowStatus(id string, flowName string) (FlowStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.findProjectID(id)
+	if i < 0 {
+		return FlowStatus{}, ErrProjectNotFound
+	}
+
+	for _, flow := range d.projectStatus(&d.projects[i]).Flows {
+		if flow.Name == flowName {
+			return flow, nil
+		}
+	}
+	return FlowStatus{}, ErrFlowNotFound
+}
+
+func (p *registeredProject) flow(flowName string) (ilofile.Flow, error) {
+	if p.err != nil {
+		return ilofile.Flow{}, fmt.Errorf("%w: %w", ErrProjectInvalid, p.err)
+	}
+	if p.definition == nil {
+		return ilofile.Flow{}, fmt.Errorf("%w: not read yet", ErrProjectInvalid)
+	}
+
+	flow, exists := p.definition.Flows[flowName]
+	if !exists {
+		return ilofile.Flow{}, fmt.Errorf("%w: '%s'", ErrFlowNotFound, flowName)
+	}
+	return flow, nil
+}
+
+// projectStatus describes a project. The lock must be held.
+func (d *IloDaemon) projectStatus(p *registeredProject) ProjectStatus {
+	status := ProjectStatus{ProjectEntry: p.entry, Flows: []FlowStatus{}}
+	if p.err != nil {
+		status.Error = p.err.Error()
+	}
+	if p.definition == nil {
+		return status
+	}
+
+	status.Name = p.definition.Name
+	for _, flow := range p.definition.Flows {
+		flowStatus := FlowStatus{
+			Name:        flow.Name,
+			Description: flow.Description,
+			Needs:       flow.Needs,
+			Schedules:   []ScheduleStatus{},
+		}
+		for i := range d.flowSchedules {
+			if scheduled := &d.flowSchedules[i]; scheduled.entry.Project == p.entry.Path && scheduled.entry.Flow == flow.Name {
+				flowStatus.Schedules = append(flowStatus.Schedules, d.scheduleStatus(scheduled))
+			}
+		}
+		status.Flows = append(status.Flows, flowStatus)
+	}
+	slices.SortFunc(status.Flows, func(a, b FlowStatus) int {
+		switch {
+		case a.Name < b.Name:
+			return -1
+		case a.Name > b.Name:
+			return 1
+		}
+		return 0
+	})
+	return status
+}
+
+func (d *IloDaemon) findProject(path string) int {
+	return slices.IndexFunc(d.projects, func(p registeredProject) bool { return p.entry.Path == path })
+}
+
+func (d *IloDaemon) findProjectID(id string) int {
+	return slices.IndexFunc(d.projects, func(p registeredProject) bool { return p.entry.ID == id })
+}
+
+// refreshProjects rereads the registered projects which have changed since
+// they were last read, updating the schedules declared in them.
+func (d *IloDaemon) refreshProjects(now time.Time) {
+	d.mu.Lock()
+	var changed []string
+	for i := range d.projects {
+		project := &d.projects[i]
+		info, err := os.Stat(project.entry.Path)
+		if err != nil {
+			if project.err == nil {
+				d.log.Warn("Project not found", "project", project.entry.Path, "error", err)
+			}
+			project.err = err
+			project.modified, project.size = time.Time{}, 0
+			continue
+		}
+		if !info.ModTime().Equal(project.modified) || info.Size() != project.size {
+			project.modified, project.size = info.ModTime(), info.Size()
+			changed = append(changed, project.entry.Path)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, path := range changed {
+		definition, err := iloyml.New(path)
+
+		d.mu.Lock()
+		// The project may have been unregistered while it was read
+		if i := d.findProject(path); i >= 0 {
+			project := &d.projects[i]
+			if err != nil {
+				// Keep the schedules as they were until the file is fixed
+				d.log.Error("Project not reloaded", "project", path, "error", err)
+				project.err = err
+			} else {
+				project.definition, project.err = definition, nil
+				d.reconcileSchedules(path, definition, now)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
+
+// declaredScheduleID identifies a schedule declared for a flow in a project
+// definition by its own ID, if it has one, or else by its expression and
+// time zone, so that it stays the same as other schedules are added,
+// removed or reordered.
+func declaredScheduleID(path string, flow string, schedule ilofile.FlowSchedule) string {
+	key := "cron\x00" + schedule.String() + "\x00" + schedule.Location().String()
+	if schedule.ID != "" {
+		key = "id\x00" + schedule.ID
+	}
+	sum := sha256.Sum256([]byte(path + "\x00" + flow + "\x00" + key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// reconcileSchedules makes the schedules declared by the project at path
+// match its definition, keeping whether each is paused. Schedules added
+// through the API are left alone. The lock must be held.
+func (d *IloDaemon) reconcileSchedules(path string, project *ilofile.Definition, now time.Time) {
+	declared := make(map[string]scheduledFlow)
+	var order []string
+
+	flowNames := make([]string, 0, len(project.Flows))
+	for flowName := range project.Flows {
+		flowNames = append(flowNames, flowName)
+	}
+	slices.Sort(flowNames)
+
+	for _, flowName := range flowNames {
+		for _, schedule := range project.Flows[flowName].Schedules {
+			zone := ""
+			if schedule.Location() != time.Local {
+				zone = schedule.Location().String()
+			}
+
+			id := declaredScheduleID(path, flowName, schedule)
+			declared[id] = scheduledFlow{
+				entry: ScheduleEntry{
+					ID:       id,
+					Project:  path,
+					Flow:     flowName,
+					Cron:     schedule.String(),
+					TimeZone: zone,
+					CatchUp:  schedule.CatchUp,
+					Overlap:  schedule.Overlap,
+					Paused:   d.scheduleState[id].Paused,
+					Declared: true,
+					Created:  now.UTC().Truncate(time.Second),
+				},
+				schedule: schedule.Schedule,
+			}
+			order = append(order, id)
+		}
+	}
+
+	stateChanged := false
+	kept := d.flowSchedules[:0]
+	for _, scheduled := range d.flowSchedules {
+		if !scheduled.entry.Declared || scheduled.entry.Project != path {
+			kept = append(kept, scheduled)
+			continue
+		}
+
+		update, exists := declared[scheduled.entry.ID]
+		if !exists {
+			d.log.Info("Schedule removed", "schedule", scheduled.entry.ID, "project", path, "flow", scheduled.entry.Flow)
+			if _, saved := d.scheduleState[scheduled.entry.ID]; saved {
+				delete(d.scheduleState, scheduled.entry.ID)
+				stateChanged = true
+			}
+			continue
+		}
+		delete(declared, scheduled.entry.ID)
+
+		// Keep whether the schedule is paused, and when it was added
+		update.entry.Paused, update.entry.Created = scheduled.entry.Paused, scheduled.entry.Created
+		if update.entry != scheduled.entry {
+			rescheduled := update.entry.Cron != scheduled.entry.Cron || update.entry.TimeZone != scheduled.entry.TimeZone
+			scheduled.entry, scheduled.schedule = update.entry, update.schedule
+			if rescheduled {
+				scheduled.reschedule(now)
+			}
+			d.log.Info("Schedule updated", "schedule", scheduled.entry.ID, "project", path, "flow", scheduled.entry.Flow,
+				"cron", scheduled.entry.Cron)
+		}
+		kept = append(kept, scheduled)
+	}
+	d.flowSchedules = kept
+
+	for _, id := range order {
+		scheduled, added := declared[id]
+		if !added {
+			continue
+		}
+		// Catch up with runs missed since the schedule last ran, such as while
+		// the server was stopped
+		since := d.lastRun(scheduled.entry)
+		if since.IsZero() {
+			since = now
+		}
+		scheduled.reschedule(since)
+		d.flowSchedules = append(d.flowSchedules, scheduled)
+		d.log.Info("Schedule added", "schedule", id, "project", path, "flow", scheduled.entry.Flow,
+			"cron", scheduled.entry.Cron)
+	}
+
+	if stateChanged {
+		if err := d.saveSchedules(); err != nil {
+			d.log.Warn("State of removed schedules not forgotten", "project", path, "error", err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fourls/ilo/internal/data/provide"
+)
+
+func TestProjectRegistry(t *testing.T) {
+	var now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := newTestDaemon(t, now)
+
+	project, added, err := d.RegisterProject(d.project)
+	if err != nil || !added {
+		t.Fatalf("got: %v, %v, want: true, nil", added, err)
+	}
+	if _, added, _ := d.RegisterProject(d.project); added {
+		t.Fatalf("got: registered twice, want: the same project")
+	}
+	if len(project.Flows) != 1 || project.Flows[0].Name != "job" {
+		t.Fatalf("got: %v, want: flow job", project.Flows)
+	}
+
+	// Changes to the definition are picked up by the next refresh, and
+	// schedules declared in it are added
+	err = os.WriteFile(d.project, []byte("flows:\n  job: {schedule: '@daily', steps: [{echo: hi}]}\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.refreshProjects(now)
+	if flow, err := d.FlowStatus(project.ID, "job"); err != nil || len(flow.Schedules) != 1 {
+		t.Fatalf("got: %v, %v, want: 1 schedule", flow.Schedules, err)
+	}
+
+	// An invalid definition keeps its flows and schedules, but reports its
+	// error and can't run
+	if err := os.WriteFile(d.project, []byte("flows: [\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d.refreshProjects(now)
+	project, err = d.Project(project.ID)
+	if err != nil || project.Error == "" || len(project.Flows) != 1 {
+		t.Fatalf("got: %v, %v, want: an error and flow job", project, err)
+	}
+	if _, err := d.ProjectFlow(project.ID, "job"); !errors.Is(err, ErrProjectInvalid) {
+		t.Fatalf("got: %v, want: %v", err, ErrProjectInvalid)
+	}
+
+	if err := d.UnregisterProject(project.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Projects()) != 0 || len(d.Schedules()) != 0 {
+		t.Fatalf("got: %d projects, %d schedules, want: none", len(d.Projects()), len(d.Schedules()))
+	}
+	if _, err := d.Project(project.ID); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("got: %v, want: %v", err, ErrProjectNotFound)
+	}
+}
+
+func TestDeclaredSchedules(t *testing.T) {
+	var now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := newTestDaemon(t, now)
+
+	var declare = func(schedules string) {
+		t.Helper()
+		err := os.WriteFile(d.project, []byte("flows:\n  job: {schedule: "+schedules+", steps: [{echo: hi}]}\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.refreshProjects(now)
+	}
+	var find = func(schedules []ScheduleStatus, cron string) ScheduleStatus {
+		t.Helper()
+		for _, schedule := range schedules {
+			if schedule.Cron == cron {
+				return schedule
+			}
+		}
+		t.Fatalf("got: %+v, want: a schedule for %s", schedules, cron)
+		return ScheduleStatus{}
+	}
+
+	declare(`["@daily", "@hourly", {id: report, cron: "0 9 * * *"}]`)
+	if _, _, err := d.RegisterProject(d.project); err != nil {
+		t.Fatal(err)
+	}
+
+	hourly := find(d.Schedules(), "@hourly")
+	report := find(d.Schedules(), "0 9 * * *")
+	for _, id := range []string{hourly.ID, report.ID} {
+		if _, err := d.PauseSchedule(id, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Paused declared schedules stay paused once the server restarts
+	reloaded := d.reload(t)
+	reloaded.refreshProjects(now)
+	if schedules := reloaded.Schedules(); len(schedules) != 3 || !find(schedules, "@hourly").Paused ||
+		!find(schedules, "0 9 * * *").Paused || find(schedules, "@daily").Paused {
+		t.Fatalf("got: %+v, want: only @hourly and the report paused", schedules)
+	}
+
+	// Schedules keep their IDs and state as others are removed and reordered,
+	// and as those with an ID of their own are changed
+	declare(`[{id: report, cron: "0 10 * * *"}, "@weekly", "@hourly"]`)
+	schedules := d.Schedules()
+	if len(schedules) != 3 {
+		t.Fatalf("got: %+v, want: 3 schedules", schedules)
+	}
+	if got := find(schedules, "@hourly"); got.ID != hourly.ID || !got.Paused {
+		t.Fatalf("got: %+v, want: ID %s and paused", got, hourly.ID)
+	}
+	if got := find(schedules, "0 10 * * *"); got.ID != report.ID || !got.Paused {
+		t.Fatalf("got: %+v, want: ID %s and paused", got, report.ID)
+	}
+	if got := find(schedules, "@weekly"); got.Paused {
+		t.Fatalf("got: %+v, want: a new schedule which isn't paused", got)
+	}
+
+	// The state of schedules removed from the definition is forgotten
+	declare(`["@weekly"]`)
+	file, err := d.scheduleFile.Load("schedules", provide.YamlUnmarshal[ScheduleFile])
+	if err != nil || len(file.State) != 0 {
+		t.Fatalf("got: %+v, %v, want: no saved state", file.State, err)
+	}
+}
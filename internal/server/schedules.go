@@ -0,0 +1,510 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fourls/ilo/internal/data"
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/history"
+	"github.com/fourls/ilo/internal/ilofile"
+)
+
+// ScheduleEntry is a flow which the server runs on a schedule. The flow is
+// stored by name, and read from its project each time it runs.
+type ScheduleEntry struct {
+	ID       string       `yaml:"id" json:"id"`
+	Project  string       `yaml:"project" json:"project"`
+	Flow     string       `yaml:"flow" json:"flow"`
+	Cron     string       `yaml:"cron" json:"cron"`
+	TimeZone string       `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	CatchUp  data.CatchUp `yaml:"catchup,omitempty" json:"catchup"`
+	Overlap  data.Overlap `yaml:"overlap,omitempty" json:"overlap"`
+	Paused   bool         `yaml:"paused,omitempty" json:"paused"`
+	// Declared is set for schedules declared in the project's ilo.yml, which
+	// are read from there rather than saved with the others.
+	Declared bool      `yaml:"-" json:"declared"`
+	Created  time.Time `yaml:"created" json:"created"`
+}
+
+// ScheduleStatus describes a schedule, along with when it will next run.
+type ScheduleStatus struct {
+	ScheduleEntry
+	ProjectID string     `json:"project_id"`
+	Next      *time.Time `json:"next,omitempty"`
+	// Running is how many of the schedule's runs haven't finished, and
+	// Queued how many are waiting for them to.
+	Running int    `json:"running"`
+	Queued  int    `json:"queued"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ScheduleFile is the file schedules are saved to.
+type ScheduleFile struct {
+	Schedules []ScheduleEntry `yaml:"schedules"`
+	// State holds what changes as schedules run, by schedule ID.
+	State map[string]ScheduleState `yaml:"state,omitempty"`
+}
+
+// ScheduleState is what changes about a schedule as it runs.
+type ScheduleState struct {
+	// LastRun is when the schedule last started its flow.
+	LastRun time.Time `yaml:"last_run,omitempty"`
+	// Paused is whether a schedule declared in a project definition is
+	// paused, as those aren't saved with the others.
+	Paused bool `yaml:"paused,omitempty"`
+}
+
+var (
+	// ErrInvalidSchedule is returned when adding a schedule whose cron
+	// expression, time zone or policies can't be parsed.
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrScheduleNotFound is returned for a schedule ID which doesn't exist.
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrScheduleDeclared is returned when removing a schedule which is
+	// declared in a project definition.
+	ErrScheduleDeclared = errors.New("schedule is declared in the project definition, and must be removed from there")
+)
+
+const (
+	// lateGrace is how late a run can start and still count as on time,
+	// rather than missed.
+	lateGrace = time.Minute
+	// maxCatchUpRuns limits how many missed runs are made up for at once.
+	maxCatchUpRuns = 100
+	// maxQueuedRuns limits how many runs wait for the last run of a
+	// schedule which queues overlapping runs.
+	maxQueuedRuns = 1
+)
+
+type scheduledFlow struct {
+	entry    ScheduleEntry
+	schedule data.Schedule
+	// err is why the schedule can't be used, if it was saved by a version
+	// of ilo which understood it differently.
+	err error
+	// next is when the flow is due to run next, or zero if it isn't.
+	next time.Time
+}
+
+// scheduleRuns tracks the runs of a schedule which haven't finished.
+type scheduleRuns struct {
+	running []*scheduledRun
+	// queued is how many runs wait for the running ones to finish, and
+	// catchUp how many missed runs are left to make up for, one after
+	// another.
+	queued  int
+	catchUp int
+}
+
+type scheduledRun struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// maxScheduleIDAttempts limits how many random IDs are tried before giving
+// up on finding one which isn't used.
+const maxScheduleIDAttempts = 10
+
+// newScheduleID returns a random ID which no schedule has yet. The lock
+// must be held.
+func (d *IloDaemon) newScheduleID() (string, error) {
+	bytes := make([]byte, 4)
+	for range maxScheduleIDAttempts {
+		if _, err := rand.Read(bytes); err != nil {
+			return "", fmt.Errorf("generate schedule ID: %w", err)
+		}
+		if id := hex.EncodeToString(bytes); d.findSchedule(id) < 0 {
+			return id, nil
+		}
+	}
+	return "", errors.New("generate schedule ID: every ID tried is already used")
+}
+
+// parseScheduleEntry parses the schedule and policies of an entry, filling
+// in the default policies.
+func parseScheduleEntry(entry *ScheduleEntry) (data.Schedule, error) {
+	schedule, err := data.ParseSchedule(entry.Cron, entry.TimeZone)
+	if err != nil {
+		return schedule, err
+	}
+	if entry.CatchUp, err = data.ParseCatchUp(string(entry.CatchUp)); err != nil {
+		return schedule, err
+	}
+	if entry.Overlap, err = data.ParseOverlap(string(entry.Overlap)); err != nil {
+		return schedule, err
+	}
+	return schedule, nil
+}
+
+// reschedule works out when the flow next runs after t.
+func (s *scheduledFlow) reschedule(t time.Time) {
+	s.next = time.Time{}
+	if s.err == nil && !s.entry.Paused {
+		s.next = s.schedule.NextAfter(t)
+	}
+}
+
+// dueRuns returns how many runs to start now that the flow is due, going
+// by its catch-up policy, along with how many runs were missed.
+func (s *scheduledFlow) dueRuns(now time.Time) (runs int, missed int) {
+	windows, last := 1, s.next
+	for windows < maxCatchUpRuns {
+		next := s.schedule.NextAfter(last)
+		if next.IsZero() || next.After(now) {
+			break
+		}
+		windows, last = windows+1, next
+	}
+
+	onTime := now.Sub(last) < lateGrace
+	missed = windows
+	if onTime {
+		missed--
+	}
+
+	switch s.entry.CatchUp {
+	case data.CatchUpAll:
+		return windows, missed
+	case data.CatchUpSkip:
+		if onTime {
+			return 1, missed
+		}
+		return 0, missed
+	default:
+		return 1, missed
+	}
+}
+
+func (d *IloDaemon) scheduleStatus(s *scheduledFlow) ScheduleStatus {
+	status := ScheduleStatus{ScheduleEntry: s.entry, ProjectID: projectID(s.entry.Project)}
+	if !s.next.IsZero() {
+		next := s.next
+		status.Next = &next
+	}
+	if runs := d.runs[s.entry.ID]; runs != nil {
+		status.Running, status.Queued = len(runs.running), runs.queued+runs.catchUp
+	}
+	if s.err != nil {
+		status.Error = s.err.Error()
+	}
+	return status
+}
+
+// lastRun returns when a schedule last started its flow, or the zero time
+// if it never has. The lock must be held.
+func (d *IloDaemon) lastRun(entry ScheduleEntry) time.Time {
+	return d.scheduleState[entry.ID].LastRun
+}
+
+// loadSchedules reads the saved schedules, registering their projects if
+// they aren't already. Runs missed since a schedule last ran, or was
+// created, are caught up with on the next tick.
+func (d *IloDaemon) loadSchedules() error {
+	file, err := d.scheduleFile.Load("schedules", provide.YamlUnmarshal[ScheduleFile])
+	if err != nil {
+		return fmt.Errorf("load schedules: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.scheduleState = file.State
+	if d.scheduleState == nil {
+		d.scheduleState = make(map[string]ScheduleState)
+	}
+
+	for _, entry := range file.Schedules {
+		scheduled := scheduledFlow{entry: entry}
+		scheduled.schedule, scheduled.err = parseScheduleEntry(&scheduled.entry)
+		if scheduled.err != nil {
+			d.log.Warn("Schedule disabled", "schedule", entry.ID, "error", scheduled.err)
+		}
+
+		since := entry.Created
+		if last := d.lastRun(entry); last.After(since) {
+			since = last
+		}
+		scheduled.reschedule(since)
+		d.flowSchedules = append(d.flowSchedules, scheduled)
+
+		if _, added, err := d.addProject(entry.Project); err != nil {
+			return err
+		} else if added {
+			d.log.Info("Project registered for schedule", "schedule", entry.ID, "project", entry.Project)
+		}
+	}
+	return nil
+}
+
+// saveSchedules writes the schedules to disk, along with their state. The
+// lock must be held.
+func (d *IloDaemon) saveSchedules() error {
+	file := ScheduleFile{State: d.scheduleState}
+	for _, scheduled := range d.flowSchedules {
+		if !scheduled.entry.Declared {
+			file.Schedules = append(file.Schedules, scheduled.entry)
+		}
+	}
+
+	if err := d.scheduleFile.Save("schedules", &file, provide.YamlMarshal); err != nil {
+		return fmt.Errorf("save schedules: %w", err)
+	}
+	return nil
+}
+
+// AddSchedule runs a flow of a registered project whenever a cron
+// expression is due, as described by entry, saving the schedule for later
+// runs of the server. The ID, project path and creation time of entry are
+// filled in.
+func (d *IloDaemon) AddSchedule(projectID string, entry ScheduleEntry) (ScheduleStatus, error) {
+	schedule, err := parseScheduleEntry(&entry)
+	if err != nil {
+		return ScheduleStatus{}, fmt.Errorf("%w: %w", ErrInvalidSchedule, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.findProjectID(projectID)
+	if i < 0 {
+		return ScheduleStatus{}, ErrProjectNotFound
+	}
+	if _, err := d.projects[i].flow(entry.Flow); err != nil {
+		return ScheduleStatus{}, err
+	}
+
+	id, err := d.newScheduleID()
+	if err != nil {
+		return ScheduleStatus{}, err
+	}
+
+	now := d.now()
+	entry.ID = id
+	entry.Project = d.projects[i].entry.Path
+	entry.Cron = schedule.String()
+	entry.Declared = false
+	entry.Created = now.UTC().Truncate(time.Second)
+
+	scheduled := scheduledFlow{entry: entry, schedule: schedule}
+	scheduled.reschedule(now)
+
+	d.flowSchedules = append(d.flowSchedules, scheduled)
+	if err := d.saveSchedules(); err != nil {
+		d.flowSchedules = d.flowSchedules[:len(d.flowSchedules)-1]
+		return ScheduleStatus{}, err
+	}
+	return d.scheduleStatus(&scheduled), nil
+}
+
+// Schedules returns every schedule, in the order they were added.
+func (d *IloDaemon) Schedules() []ScheduleStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	statuses := make([]ScheduleStatus, len(d.flowSchedules))
+	for i := range d.flowSchedules {
+		statuses[i] = d.scheduleStatus(&d.flowSchedules[i])
+	}
+	return statuses
+}
+
+func (d *IloDaemon) findSchedule(id string) int {
+	return slices.IndexFunc(d.flowSchedules, func(s scheduledFlow) bool { return s.entry.ID == id })
+}
+
+// RemoveSchedule stops running a scheduled flow. Runs which have already
+// started carry on, but queued runs don't start.
+func (d *IloDaemon) RemoveSchedule(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.findSchedule(id)
+	if i < 0 {
+		return ErrScheduleNotFound
+	}
+	if d.flowSchedules[i].entry.Declared {
+		return ErrScheduleDeclared
+	}
+
+	removed := d.flowSchedules[i]
+	state, hadState := d.scheduleState[id]
+	d.flowSchedules = slices.Delete(d.flowSchedules, i, i+1)
+	delete(d.scheduleState, id)
+	if err := d.saveSchedules(); err != nil {
+		d.flowSchedules = slices.Insert(d.flowSchedules, i, removed)
+		if hadState {
+			d.scheduleState[id] = state
+		}
+		return err
+	}
+	return nil
+}
+
+// PauseSchedule stops or resumes running a scheduled flow. A resumed flow
+// next runs when it is next due, rather than making up for missed runs.
+func (d *IloDaemon) PauseSchedule(id string, paused bool) (ScheduleStatus, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	i := d.findSchedule(id)
+	if i < 0 {
+		return ScheduleStatus{}, ErrScheduleNotFound
+	}
+
+	scheduled := &d.flowSchedules[i]
+	previous := *scheduled
+	previousState, hadState := d.scheduleState[id]
+	scheduled.entry.Paused = paused
+	scheduled.reschedule(d.now())
+	if scheduled.entry.Declared {
+		state := previousState
+		state.Paused = paused
+		d.setScheduleState(id, state)
+	}
+
+	if err := d.saveSchedules(); err != nil {
+		*scheduled = previous
+		if hadState {
+			d.scheduleState[id] = previousState
+		} else {
+			delete(d.scheduleState, id)
+		}
+		return ScheduleStatus{}, err
+	}
+	return d.scheduleStatus(scheduled), nil
+}
+
+// setScheduleState changes the saved state of a schedule, forgetting it
+// once there is nothing left to save. The lock must be held.
+func (d *IloDaemon) setScheduleState(id string, state ScheduleState) {
+	if state == (ScheduleState{}) {
+		delete(d.scheduleState, id)
+		return
+	}
+	if d.scheduleState == nil {
+		d.scheduleState = make(map[string]ScheduleState)
+	}
+	d.scheduleState[id] = state
+}
+
+// admitRun applies a schedule's overlap policy to a run which is due,
+// returning the run to start, or nil if it isn't to start now. The lock
+// must be held.
+func (d *IloDaemon) admitRun(entry ScheduleEntry) *scheduledRun {
+	runs := d.scheduleRuns(entry.ID)
+
+	if len(runs.running) > 0 {
+		switch entry.Overlap {
+		case data.OverlapSkip:
+			d.log.Info("Scheduled run skipped, as the last run hasn't finished", "schedule", entry.ID, "flow", entry.Flow)
+			return nil
+		case data.OverlapQueue:
+			if runs.queued >= maxQueuedRuns {
+				d.log.Warn("Scheduled run dropped, as a run is already queued", "schedule", entry.ID, "flow", entry.Flow)
+				return nil
+			}
+			runs.queued++
+			d.log.Info("Scheduled run queued until the last run finishes", "schedule", entry.ID, "flow", entry.Flow)
+			return nil
+		case data.OverlapCancel:
+			for _, run := range runs.running {
+				run.cancel()
+			}
+			runs.running = nil
+			d.log.Info("Last scheduled run cancelled", "schedule", entry.ID, "flow", entry.Flow)
+		}
+	}
+
+	return runs.start()
+}
+
+// scheduleRuns returns the unfinished runs of a schedule, tracking them if
+// it has none. The lock must be held.
+func (d *IloDaemon) scheduleRuns(id string) *scheduleRuns {
+	runs := d.runs[id]
+	if runs == nil {
+		runs = &scheduleRuns{}
+		d.runs[id] = runs
+	}
+	return runs
+}
+
+func (r *scheduleRuns) start() *scheduledRun {
+	run := &scheduledRun{}
+	run.ctx, run.cancel = context.WithCancel(context.Background())
+	r.running = append(r.running, run)
+	return run
+}
+
+// finishRun stops tracking a run once it finishes. Once no runs are left,
+// missed runs still to be made up for start, and then queued runs.
+func (d *IloDaemon) finishRun(id string, run *scheduledRun) {
+	d.mu.Lock()
+	run.cancel()
+
+	var next *scheduledRun
+	var entry ScheduleEntry
+	if runs := d.runs[id]; runs != nil {
+		runs.running = slices.DeleteFunc(runs.running, func(r *scheduledRun) bool { return r == run })
+
+		// Runs waiting on a schedule which has since been removed or paused
+		// are dropped
+		if i := d.findSchedule(id); i < 0 || d.flowSchedules[i].entry.Paused {
+			runs.queued, runs.catchUp = 0, 0
+		} else if len(runs.running) == 0 && runs.catchUp+runs.queued > 0 {
+			if runs.catchUp > 0 {
+				runs.catchUp--
+			} else {
+				runs.queued--
+			}
+			entry = d.flowSchedules[i].entry
+			next = runs.start()
+		}
+
+		if len(runs.running) == 0 && runs.queued == 0 && runs.catchUp == 0 {
+			delete(d.runs, id)
+		}
+	}
+	d.mu.Unlock()
+
+	if next != nil {
+		d.runScheduled(entry, next)
+	}
+}
+
+// runScheduled starts a scheduled flow as its project was last read, so
+// that changes made to the project since it was scheduled are used.
+func (d *IloDaemon) runScheduled(entry ScheduleEntry, run *scheduledRun) {
+	d.mu.Lock()
+	var flow ilofile.Flow
+	var err error
+	if i := d.findProject(entry.Project); i < 0 {
+		err = ErrProjectNotFound
+	} else {
+		flow, err = d.projects[i].flow(entry.Flow)
+	}
+
+	if err == nil && d.findSchedule(entry.ID) >= 0 {
+		state := d.scheduleState[entry.ID]
+		state.LastRun = d.now().UTC()
+		d.setScheduleState(entry.ID, state)
+		if err := d.saveSchedules(); err != nil {
+			d.log.Warn("Last scheduled run not saved", "schedule", entry.ID, "error", err)
+		}
+	}
+	d.mu.Unlock()
+
+	if err != nil {
+		d.log.Error("Scheduled flow not started", "schedule", entry.ID, "project", entry.Project, "flow", entry.Flow, "error", err)
+		d.finishRun(entry.ID, run)
+		return
+	}
+
+	d.startFlow(run.ctx, flow, history.TriggerSchedule, func() { d.finishRun(entry.ID, run) })
+}
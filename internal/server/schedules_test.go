@@ -0,0 +1,294 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fourls/ilo/internal/data"
+	"github.com/fourls/ilo/internal/data/provide"
+	"github.com/fourls/ilo/internal/exec"
+	"github.com/fourls/ilo/internal/history"
+	"github.com/fourls/ilo/internal/ilofile"
+	"github.com/fourls/ilo/internal/runlog"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// testDaemon is a daemon whose steps block until released or cancelled.
+type testDaemon struct {
+	*IloDaemon
+	clock   *fakeClock
+	project string
+	// started receives the text of each step as it starts, and cancelled
+	// receives one for each step stopped by its context.
+	started   chan string
+	cancelled chan struct{}
+	release   chan struct{}
+}
+
+func newTestDaemon(t *testing.T, now time.Time) *testDaemon {
+	dir := t.TempDir()
+	project := filepath.Join(dir, "ilo.yml")
+	if err := os.WriteFile(project, []byte("flows:\n  job: [{echo: hi}]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &testDaemon{
+		clock:     &fakeClock{now: now},
+		project:   project,
+		started:   make(chan string, 16),
+		cancelled: make(chan struct{}, 16),
+		release:   make(chan struct{}),
+	}
+	d.IloDaemon = &IloDaemon{
+		clock:        d.clock,
+		log:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		logs:         runlog.Store{Dir: filepath.Join(dir, "logs")},
+		history:      history.Store{Path: filepath.Join(dir, "history.jsonl")},
+		scheduleFile: provide.NewFileProvider[ScheduleFile](dir),
+		projectFile:  provide.NewFileProvider[ProjectFile](dir),
+		runs:         make(map[string]*scheduleRuns),
+		runStep: func(step ilofile.Step, params exec.ExecParams) error {
+			d.started <- step.String()
+			select {
+			case <-d.release:
+				return nil
+			case <-params.Context.Done():
+				d.cancelled <- struct{}{}
+				return params.Context.Err()
+			}
+		},
+	}
+
+	t.Cleanup(func() {
+		select {
+		case <-d.release:
+		default:
+			close(d.release)
+		}
+		d.waitIdle(t)
+	})
+	return d
+}
+
+// waitStarted waits for a step to start, returning its text.
+func (d *testDaemon) waitStarted(t *testing.T) string {
+	t.Helper()
+	select {
+	case step := <-d.started:
+		return step
+	case <-time.After(5 * time.Second):
+		t.Fatalf("got: no run started, want: a run to start")
+		return ""
+	}
+}
+
+// waitIdle waits for every scheduled run to finish.
+func (d *testDaemon) waitIdle(t *testing.T) {
+	for range 500 {
+		d.mu.Lock()
+		idle := len(d.runs) == 0
+		d.mu.Unlock()
+		if idle {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("got: scheduled runs still going, want: none")
+}
+
+func (d *testDaemon) status(t *testing.T) ScheduleStatus {
+	t.Helper()
+	schedules := d.Schedules()
+	if len(schedules) != 1 {
+		t.Fatalf("got: %d schedules, want: 1", len(schedules))
+	}
+	return schedules[0]
+}
+
+func TestDueRuns(t *testing.T) {
+	var start = time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	var tests = []struct {
+		now     time.Time
+		catchUp data.CatchUp
+		runs    int
+		missed  int
+	}{
+		{start.Add(time.Second), data.CatchUpOnce, 1, 0},
+		{start.Add(time.Second), data.CatchUpAll, 1, 0},
+		{start.Add(time.Second), data.CatchUpSkip, 1, 0},
+		// Asleep from before 01:00 until 04:30
+		{start.Add(3*time.Hour + 30*time.Minute), data.CatchUpOnce, 1, 4},
+		{start.Add(3*time.Hour + 30*time.Minute), data.CatchUpAll, 4, 4},
+		{start.Add(3*time.Hour + 30*time.Minute), data.CatchUpSkip, 0, 4},
+		// Asleep until just after 04:00, which is still on time
+		{start.Add(3*time.Hour + 10*time.Second), data.CatchUpOnce, 1, 3},
+		{start.Add(3*time.Hour + 10*time.Second), data.CatchUpAll, 4, 3},
+		{start.Add(3*time.Hour + 10*time.Second), data.CatchUpSkip, 1, 3},
+	}
+
+	schedule, err := data.ParseSchedule("@hourly", "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range tests {
+		scheduled := scheduledFlow{entry: ScheduleEntry{CatchUp: tc.catchUp}, schedule: schedule, next: start}
+		runs, missed := scheduled.dueRuns(tc.now)
+		if runs != tc.runs || missed != tc.missed {
+			t.Fatalf("%s at %s got: %d runs, %d missed, want: %d runs, %d missed",
+				tc.catchUp, tc.now.Format(time.TimeOnly), runs, missed, tc.runs, tc.missed)
+		}
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	var start = time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)
+	var tests = []struct {
+		overlap data.Overlap
+		// running and queued are how many runs there are once the flow is
+		// due again while the first run is going.
+		running, queued int
+	}{
+		{data.OverlapAllow, 2, 0},
+		{data.OverlapSkip, 1, 0},
+		{data.OverlapQueue, 1, 1},
+		{data.OverlapCancel, 1, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.overlap), func(t *testing.T) {
+			d := newTestDaemon(t, start)
+			project, _, err := d.RegisterProject(d.project)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = d.AddSchedule(project.ID, ScheduleEntry{
+				Flow: "job", Cron: "* * * * *", TimeZone: "UTC", Overlap: tc.overlap,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			d.tick(start.Add(30 * time.Second))
+			d.waitStarted(t)
+
+			d.tick(start.Add(90 * time.Second))
+			status := d.status(t)
+			if status.Running != tc.running || status.Queued != tc.queued {
+				t.Fatalf("got: %d running, %d queued, want: %d running, %d queued",
+					status.Running, status.Queued, tc.running, tc.queued)
+			}
+
+			switch tc.overlap {
+			case data.OverlapAllow:
+				d.waitStarted(t)
+			case data.OverlapQueue:
+				// Only one run waits, and any more are dropped
+				d.tick(start.Add(150 * time.Second))
+				if status := d.status(t); status.Queued != 1 {
+					t.Fatalf("got: %d queued, want: 1", status.Queued)
+				}
+				close(d.release)
+				d.waitStarted(t)
+			case data.OverlapCancel:
+				select {
+				case <-d.cancelled:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("got: first run not cancelled, want: cancelled")
+				}
+				d.waitStarted(t)
+			}
+		})
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	var created = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var now = created.Add(4*time.Hour + 30*time.Minute)
+	var tests = []struct {
+		catchUp data.CatchUp
+		// runs is how many runs there are in all, one after another
+		runs int
+	}{
+		{data.CatchUpOnce, 1},
+		{data.CatchUpAll, 3},
+		{data.CatchUpSkip, 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.catchUp), func(t *testing.T) {
+			d := newTestDaemon(t, now)
+
+			// The last run was at 01:00, so those at 02:00, 03:00 and 04:00
+			// were missed
+			var last = created.Add(time.Hour + 5*time.Second)
+			err := d.scheduleFile.Save("schedules", &ScheduleFile{
+				Schedules: []ScheduleEntry{{
+					ID: "abc", Project: d.project, Flow: "job", Cron: "@hourly", TimeZone: "UTC",
+					CatchUp: tc.catchUp, Created: created,
+				}},
+				State: map[string]ScheduleState{"abc": {LastRun: last}},
+			}, provide.YamlMarshal)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := d.loadSchedules(); err != nil {
+				t.Fatal(err)
+			}
+			d.refreshProjects(now)
+			if next := d.status(t).Next; next == nil || !next.Equal(created.Add(2*time.Hour)) {
+				t.Fatalf("got: next run at %v, want: 02:00", next)
+			}
+
+			d.tick(now)
+			status := d.status(t)
+			if status.Running != min(tc.runs, 1) || status.Queued != max(tc.runs-1, 0) {
+				t.Fatalf("got: %d running, %d queued, want: %d runs one after another",
+					status.Running, status.Queued, tc.runs)
+			}
+			if status.Next == nil || !status.Next.Equal(created.Add(5*time.Hour)) {
+				t.Fatalf("got: next run at %v, want: 05:00", status.Next)
+			}
+
+			close(d.release)
+			for range tc.runs {
+				d.waitStarted(t)
+			}
+			d.waitIdle(t)
+
+			file, err := d.scheduleFile.Load("schedules", provide.YamlUnmarshal[ScheduleFile])
+			if err != nil {
+				t.Fatal(err)
+			}
+			var expected = last
+			if tc.runs > 0 {
+				expected = now
+			}
+			if got := file.State["abc"].LastRun; !got.Equal(expected) {
+				t.Fatalf("got: last run at %v, want: %v", got, expected)
+			}
+		})
+	}
+}
@@ -1,89 +1,262 @@
 package server
 
 import (
-	"fmt"
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
-	"strconv"
-	"time"
 
 	"github.com/fourls/ilo/internal/data"
 	"github.com/fourls/ilo/internal/data/provide"
 	"github.com/fourls/ilo/internal/data/toolbox"
+	"github.com/fourls/ilo/internal/history"
 	"github.com/fourls/ilo/internal/ilofile/iloyml"
+	"github.com/fourls/ilo/internal/runlog"
 	"github.com/gin-gonic/gin"
 )
 
 func BuildServer(provider provide.Provider[toolbox.Toolbox]) *gin.Engine {
-	r := gin.Default()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	// Project tools are layered over these when each flow runs
+	tools, err := toolbox.LoadLayered(provider, "", nil)
+	if err != nil {
+		logger.Error("Toolbox not loaded", "error", err)
+	}
+
+	retention, err := runlog.LoadRetention(provide.NewConfigProvider[runlog.Retention]())
+	if err != nil {
+		logger.Warn("Using default log retention", "error", err)
+		retention = runlog.Retention{MaxAge: runlog.DefaultMaxAge, MaxSize: runlog.DefaultMaxSize}
+	}
 
-	toolbox, _ := provider.Load(
-		"toolbox",
-		provide.YamlUnmarshal[toolbox.Toolbox])
+	historyRetention, err := history.LoadRetention(provide.NewConfigProvider[history.Retention]())
+	if err != nil {
+		logger.Warn("Using default history retention", "error", err)
+		historyRetention = history.Retention{MaxAge: history.DefaultMaxAge, MaxSize: history.DefaultMaxSize}
+	}
 
 	daemon := IloDaemon{
-		toolbox: *toolbox,
-		log:     slog.New(slog.NewTextHandler(os.Stdout, nil)),
+		clock:            systemClock{},
+		runs:             make(map[string]*scheduleRuns),
+		toolbox:          tools,
+		log:              logger,
+		logs:             runlog.NewCacheStore(),
+		history:          history.NewConfigStore(),
+		retention:        retention,
+		historyRetention: historyRetention,
+		scheduleFile:     provide.NewConfigProvider[ScheduleFile](),
+		projectFile:      provide.NewConfigProvider[ProjectFile](),
+	}
+	if err := daemon.loadProjects(); err != nil {
+		logger.Error("Projects not loaded", "error", err)
+	}
+	if err := daemon.loadSchedules(); err != nil {
+		logger.Error("Schedules not loaded", "error", err)
 	}
+	daemon.refreshProjects(daemon.now())
 	daemon.Run()
 
-	r.POST("/api/flows/exec", func(c *gin.Context) {
-		projectPath := c.Query("project")
-		flowName := c.Query("flow")
+	return buildRouter(&daemon)
+}
 
-		project, err := iloyml.New(projectPath)
+// buildRouter serves the API of a daemon.
+func buildRouter(daemon *IloDaemon) *gin.Engine {
+	r := gin.Default()
+
+	r.GET("/api/projects", func(c *gin.Context) {
+		c.JSON(http.StatusOK, daemon.Projects())
+	})
+
+	r.POST("/api/projects", func(c *gin.Context) {
+		status, added, ok := registerQueryProject(c, daemon)
+		if !ok {
+			return
+		}
+
+		if added {
+			c.JSON(http.StatusCreated, status)
+		} else {
+			c.JSON(http.StatusOK, status)
+		}
+	})
+
+	r.GET("/api/projects/:id", func(c *gin.Context) {
+		status, err := daemon.Project(c.Param("id"))
 		if err != nil {
-			c.JSON(http.StatusBadRequest, map[string]any{
+			c.JSON(errorStatus(err), map[string]any{
 				"error": err.Error(),
 			})
 			return
 		}
+		c.JSON(http.StatusOK, status)
+	})
 
-		flow, exists := project.Flows[flowName]
+	r.DELETE("/api/projects/:id", func(c *gin.Context) {
+		if err := daemon.UnregisterProject(c.Param("id")); err != nil {
+			c.JSON(errorStatus(err), map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
 
-		if exists {
-			daemon.RunFlow(flow)
-			c.Status(http.StatusNoContent)
-		} else {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error": fmt.Sprintf("flow '%s' does not exist", flowName),
+	r.GET("/api/projects/:id/flows/:flow", func(c *gin.Context) {
+		status, err := daemon.FlowStatus(c.Param("id"), c.Param("flow"))
+		if err != nil {
+			c.JSON(errorStatus(err), map[string]any{
+				"error": err.Error(),
 			})
+			return
 		}
+		c.JSON(http.StatusOK, status)
 	})
 
-	r.POST("/api/schedules", func(c *gin.Context) {
-		projectPath := c.Query("project")
-		flowName := c.Query("flow")
-		scheduleDay, _ := strconv.Atoi(c.Query("day"))
-		scheduleHour, _ := strconv.Atoi(c.Query("hour"))
-		scheduleMinute, _ := strconv.Atoi(c.Query("minute"))
+	r.POST("/api/projects/:id/flows/:flow/exec", func(c *gin.Context) {
+		flow, err := daemon.ProjectFlow(c.Param("id"), c.Param("flow"))
+		if err != nil {
+			c.JSON(errorStatus(err), map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		daemon.RunFlow(flow, history.TriggerAPI)
+		c.Status(http.StatusNoContent)
+	})
 
-		project, err := iloyml.New(projectPath)
+	r.POST("/api/projects/:id/flows/:flow/schedules", func(c *gin.Context) {
+		status, err := daemon.AddSchedule(c.Param("id"), ScheduleEntry{
+			Flow:     c.Param("flow"),
+			Cron:     c.Query("cron"),
+			TimeZone: c.Query("tz"),
+			CatchUp:  data.CatchUp(c.Query("catchup")),
+			Overlap:  data.Overlap(c.Query("overlap")),
+		})
 		if err != nil {
-			c.JSON(http.StatusBadRequest, map[string]any{
+			c.JSON(errorStatus(err), map[string]any{
 				"error": err.Error(),
 			})
 			return
 		}
+		c.JSON(http.StatusCreated, status)
+	})
 
-		flow, exists := project.Flows[flowName]
+	// Before projects were registered, flows were run and scheduled by the
+	// path of their project. These register the project and carry on as the
+	// routes that replaced them.
+	r.POST("/api/flows/exec", func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		project, _, ok := registerQueryProject(c, daemon)
+		if !ok {
+			return
+		}
 
-		if exists {
-			schedule := data.Schedule{Minute: scheduleMinute, Hour: scheduleHour, Day: time.Weekday(scheduleDay)}
+		flow, err := daemon.ProjectFlow(project.ID, c.Query("flow"))
+		if err != nil {
+			c.JSON(errorStatus(err), map[string]any{
+				"error": err.Error(),
+			})
+			return
+		}
 
-			daemon.ScheduleFlow(flow, schedule)
-			c.JSON(http.StatusOK, map[string]any{
-				"project":  projectPath,
-				"flow":     flowName,
-				"schedule": schedule.String(),
+		daemon.RunFlow(flow, history.TriggerAPI)
+		c.Status(http.StatusNoContent)
+	})
+
+	r.POST("/api/schedules", func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		project, _, ok := registerQueryProject(c, daemon)
+		if !ok {
+			return
+		}
+
+		status, err := daemon.AddSchedule(project.ID, ScheduleEntry{
+			Flow:     c.Query("flow"),
+			Cron:     c.Query("cron"),
+			TimeZone: c.Query("tz"),
+			CatchUp:  data.CatchUp(c.Query("catchup")),
+			Overlap:  data.Overlap(c.Query("overlap")),
+		})
+		if err != nil {
+			c.JSON(errorStatus(err), map[string]any{
+				"error": err.Error(),
 			})
-		} else {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error": fmt.Sprintf("flow '%s' does not exist", flowName),
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	})
+
+	r.GET("/api/schedules", func(c *gin.Context) {
+		c.JSON(http.StatusOK, daemon.Schedules())
+	})
+
+	r.DELETE("/api/schedules/:id", func(c *gin.Context) {
+		if err := daemon.RemoveSchedule(c.Param("id")); err != nil {
+			c.JSON(errorStatus(err), map[string]any{
+				"error": err.Error(),
 			})
+			return
 		}
+		c.Status(http.StatusNoContent)
 	})
 
+	for action, paused := range map[string]bool{"pause": true, "resume": false} {
+		r.POST("/api/schedules/:id/"+action, func(c *gin.Context) {
+			status, err := daemon.PauseSchedule(c.Param("id"), paused)
+			if err != nil {
+				c.JSON(errorStatus(err), map[string]any{
+					"error": err.Error(),
+				})
+				return
+			}
+			c.JSON(http.StatusOK, status)
+		})
+	}
+
 	return r
 }
+
+// registerQueryProject registers the project given by the project query
+// parameter, responding with an error if it can't be.
+func registerQueryProject(c *gin.Context, daemon *IloDaemon) (ProjectStatus, bool, bool) {
+	path := c.Query("project")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, map[string]any{
+			"error": "missing 'project' parameter",
+		})
+		return ProjectStatus{}, false, false
+	}
+
+	projectPath, err := iloyml.Locate(path)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]any{
+			"error": err.Error(),
+		})
+		return ProjectStatus{}, false, false
+	}
+
+	status, added, err := daemon.RegisterProject(projectPath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]any{
+			"error": err.Error(),
+		})
+		return ProjectStatus{}, false, false
+	}
+	return status, added, true
+}
+
+// errorStatus returns the HTTP status for an error from the daemon.
+// Anything not caused by the request is an internal error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrProjectNotFound), errors.Is(err, ErrFlowNotFound), errors.Is(err, ErrScheduleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrProjectInvalid), errors.Is(err, ErrScheduleDeclared):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidSchedule):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
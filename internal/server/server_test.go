@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// request sends a request to the API, decoding any JSON response into out.
+func request(t *testing.T, router http.Handler, method string, target string, out any) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	if out != nil && recorder.Body.Len() > 0 {
+		if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+			t.Fatalf("got: %v decoding %s, want: nil", err, recorder.Body)
+		}
+	}
+	return recorder.Code
+}
+
+// reload returns a daemon which loads the schedules and projects d saved.
+func (d *testDaemon) reload(t *testing.T) *IloDaemon {
+	t.Helper()
+	reloaded := &IloDaemon{
+		clock:        d.clock,
+		log:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+		history:      d.history,
+		scheduleFile: d.scheduleFile,
+		projectFile:  d.projectFile,
+		runs:         make(map[string]*scheduleRuns),
+	}
+	if err := reloaded.loadProjects(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.loadSchedules(); err != nil {
+		t.Fatal(err)
+	}
+	return reloaded
+}
+
+func TestScheduleAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var now = time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)
+	d := newTestDaemon(t, now)
+	router := buildRouter(d.IloDaemon)
+
+	var project ProjectStatus
+	if code := request(t, router, http.MethodPost, "/api/projects?project="+url.QueryEscape(d.project), &project); code != http.StatusCreated {
+		t.Fatalf("got: %d, want: %d", code, http.StatusCreated)
+	}
+	var schedulesPath = "/api/projects/" + project.ID + "/flows/job/schedules"
+
+	for target, expected := range map[string]int{
+		schedulesPath + "?cron=nonsense":                                       http.StatusBadRequest,
+		schedulesPath + "?cron=*+*+*+*+*&tz=Nowhere/Special":                   http.StatusBadRequest,
+		"/api/projects/" + project.ID + "/flows/missing/schedules?cron=@daily": http.StatusNotFound,
+		"/api/projects/missing/flows/job/schedules?cron=@daily":                http.StatusNotFound,
+	} {
+		if code := request(t, router, http.MethodPost, target, nil); code != expected {
+			t.Fatalf("POST %s got: %d, want: %d", target, code, expected)
+		}
+	}
+
+	var added ScheduleStatus
+	if code := request(t, router, http.MethodPost, schedulesPath+"?cron=*+*+*+*+*&tz=UTC", &added); code != http.StatusCreated {
+		t.Fatalf("got: %d, want: %d", code, http.StatusCreated)
+	}
+	if added.ID == "" || added.Next == nil || !added.Next.Equal(now.Add(30*time.Second)) {
+		t.Fatalf("got: %+v, want: an ID and next run at 00:01", added)
+	}
+
+	var listed []ScheduleStatus
+	if code := request(t, router, http.MethodGet, "/api/schedules", &listed); code != http.StatusOK ||
+		len(listed) != 1 || listed[0].ID != added.ID || listed[0].ProjectID != project.ID {
+		t.Fatalf("got: %d, %+v, want: the added schedule", code, listed)
+	}
+
+	// Saved schedules are loaded again, with the same ID and next run
+	if reloaded := d.reload(t).Schedules(); len(reloaded) != 1 || reloaded[0].ID != added.ID ||
+		reloaded[0].Next == nil || !reloaded[0].Next.Equal(*added.Next) {
+		t.Fatalf("got: %+v, want: the added schedule", reloaded)
+	}
+
+	var paused ScheduleStatus
+	if code := request(t, router, http.MethodPost, "/api/schedules/"+added.ID+"/pause", &paused); code != http.StatusOK ||
+		!paused.Paused || paused.Next != nil {
+		t.Fatalf("got: %d, %+v, want: paused with no next run", code, paused)
+	}
+	if reloaded := d.reload(t).Schedules(); len(reloaded) != 1 || !reloaded[0].Paused {
+		t.Fatalf("got: %+v, want: still paused once reloaded", reloaded)
+	}
+
+	var resumed ScheduleStatus
+	if code := request(t, router, http.MethodPost, "/api/schedules/"+added.ID+"/resume", &resumed); code != http.StatusOK ||
+		resumed.Paused || resumed.Next == nil {
+		t.Fatalf("got: %d, %+v, want: resumed with a next run", code, resumed)
+	}
+
+	// The flow is read as the project was last loaded each time it runs
+	if err := os.WriteFile(d.project, []byte("flows:\n  job: [{echo: changed}]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d.refreshProjects(now)
+	d.tick(*resumed.Next)
+	if step := d.waitStarted(t); !strings.Contains(step, "changed") {
+		t.Fatalf("got: step %q, want: the changed flow", step)
+	}
+
+	if code := request(t, router, http.MethodDelete, "/api/schedules/"+added.ID, nil); code != http.StatusNoContent {
+		t.Fatalf("got: %d, want: %d", code, http.StatusNoContent)
+	}
+	if code := request(t, router, http.MethodDelete, "/api/schedules/"+added.ID, nil); code != http.StatusNotFound {
+		t.Fatalf("got: %d, want: %d", code, http.StatusNotFound)
+	}
+	for _, action := range []string{"pause", "resume"} {
+		if code := request(t, router, http.MethodPost, "/api/schedules/"+added.ID+"/"+action, nil); code != http.StatusNotFound {
+			t.Fatalf("%s got: %d, want: %d", action, code, http.StatusNotFound)
+		}
+	}
+	if code := request(t, router, http.MethodGet, "/api/schedules", &listed); code != http.StatusOK || len(listed) != 0 {
+		t.Fatalf("got: %d, %+v, want: no schedules", code, listed)
+	}
+	if reloaded := d.reload(t).Schedules(); len(reloaded) != 0 {
+		t.Fatalf("got: %+v, want: no schedules once reloaded", reloaded)
+	}
+}
+
+func TestProjectPathRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var now = time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC)
+	d := newTestDaemon(t, now)
+	router := buildRouter(d.IloDaemon)
+	var project = url.QueryEscape(d.project)
+
+	for _, target := range []string{"/api/projects", "/api/flows/exec?flow=job", "/api/schedules?flow=job&cron=@daily"} {
+		if code := request(t, router, http.MethodPost, target, nil); code != http.StatusBadRequest {
+			t.Fatalf("POST %s got: %d, want: %d without a project", target, code, http.StatusBadRequest)
+		}
+	}
+	if projects := d.Projects(); len(projects) != 0 {
+		t.Fatalf("got: %+v, want: no projects registered", projects)
+	}
+
+	var added ScheduleStatus
+	if code := request(t, router, http.MethodPost, "/api/schedules?project="+project+"&flow=job&cron=@daily&tz=UTC", &added); code != http.StatusOK {
+		t.Fatalf("got: %d, want: %d", code, http.StatusOK)
+	}
+	if projects := d.Projects(); len(projects) != 1 || projects[0].Path != d.project || added.ProjectID != projects[0].ID {
+		t.Fatalf("got: %+v and %+v, want: the project registered and scheduled", projects, added)
+	}
+
+	if code := request(t, router, http.MethodPost, "/api/flows/exec?project="+project+"&flow=missing", nil); code != http.StatusNotFound {
+		t.Fatalf("got: %d, want: %d", code, http.StatusNotFound)
+	}
+	if code := request(t, router, http.MethodPost, "/api/flows/exec?project="+project+"&flow=job", nil); code != http.StatusNoContent {
+		t.Fatalf("got: %d, want: %d", code, http.StatusNoContent)
+	}
+	if step := d.waitStarted(t); step != "hi" {
+		t.Fatalf("got: step %q, want: hi", step)
+	}
+}